@@ -0,0 +1,309 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/models"
+	"taskmanager/ranking"
+)
+
+// Migrate brings the schema up to date and converts data written by older
+// versions of the application.
+func Migrate() error {
+	hadAllDayColumn := DB.Dialect().HasColumn("tasks", "due_all_day")
+	hadStatusTimestamps := DB.Dialect().HasColumn("tasks", "started_at")
+
+	if err := DB.AutoMigrate(
+		&models.User{},
+		&models.Task{},
+		&models.Comment{},
+		&models.CommentRevision{},
+		&models.Attachment{},
+		&models.Project{},
+		&models.TaskStatusChange{},
+		&models.TaskStatus{},
+		&models.TaskPriority{},
+		&models.TimeEntry{},
+		&models.Tag{},
+		&models.TaskRevision{},
+		&models.CalendarObject{},
+		&models.TaskTemplate{},
+		&models.TaskTemplateSubtask{},
+		&models.SavedView{},
+		&models.Share{},
+		&models.Workspace{},
+		&models.Membership{},
+		&models.Mention{},
+		&models.Notification{},
+	).Error; err != nil {
+		return err
+	}
+
+	// Only one timer per user may run at a time. A partial index can't be
+	// declared through struct tags, so it is created here.
+	if err := DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries (user_id) WHERE stopped_at IS NULL AND deleted_at IS NULL").Error; err != nil {
+		return err
+	}
+
+	// Likewise, each user has at most one default view.
+	if err := DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_saved_views_default ON saved_views (user_id) WHERE is_default").Error; err != nil {
+		return err
+	}
+
+	if err := BackfillTaskRanks(); err != nil {
+		return fmt.Errorf("assigning task ranks: %w", err)
+	}
+	if !hadStatusTimestamps {
+		if err := BackfillStatusTimestamps(); err != nil {
+			return fmt.Errorf("backfilling status timestamps: %w", err)
+		}
+	}
+	if err := SeedAllTaskDefinitions(); err != nil {
+		return fmt.Errorf("migrating statuses and priorities: %w", err)
+	}
+	if err := ClearZeroDueDates(); err != nil {
+		return fmt.Errorf("clearing empty due dates: %w", err)
+	}
+	if !hadAllDayColumn {
+		if err := BackfillAllDayDueDates(); err != nil {
+			return fmt.Errorf("marking all-day due dates: %w", err)
+		}
+	}
+	if err := BindPendingShares(); err != nil {
+		return fmt.Errorf("binding pending invitations: %w", err)
+	}
+	return nil
+}
+
+// BindPendingShares binds pending invitations from before invitations were
+// bound to users: each goes to the user who had registered its address by
+// the time it was sent. Invitations to addresses registered only later
+// have no invite token and can't be accepted; the owner has to invite
+// again.
+func BindPendingShares() error {
+	result := DB.Exec(`UPDATE shares SET user_id = users.id FROM users
+		WHERE shares.user_id IS NULL AND shares.accepted_at IS NULL AND shares.invite_token_hash IS NULL
+		AND LOWER(users.email) = shares.email AND users.created_at <= shares.created_at`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Bound %d pending invitations to their users", result.RowsAffected)
+	}
+	return nil
+}
+
+// ClearZeroDueDates turns due dates stored as Go's zero time into NULL.
+// Before the column became optional, clearing due_date through PATCH wrote
+// the zero time; those are the only rows it affects. Tasks created without
+// a due date were given the creation time or that day instead, which can't
+// be told apart from a chosen date, so they keep it.
+func ClearZeroDueDates() error {
+	result := DB.Model(&models.Task{}).Unscoped().
+		Where("due_date < ?", "0002-01-01").
+		UpdateColumn("due_date", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Cleared %d empty due dates", result.RowsAffected)
+	}
+	return nil
+}
+
+// BackfillAllDayDueDates marks due dates written before the all-day flag
+// existed. Those were stored as UTC midnight of the chosen day, so any due
+// date exactly at UTC midnight is taken to be an all-day date. It runs only
+// when the column is first added, as later exact instants may also fall on
+// midnight.
+func BackfillAllDayDueDates() error {
+	result := DB.Exec("UPDATE tasks SET due_all_day = true WHERE (due_date AT TIME ZONE 'UTC')::time = '00:00'")
+	if result.Error != nil {
+		return result.Error
+	}
+	log.Printf("Marked %d existing due dates as all-day", result.RowsAffected)
+	return nil
+}
+
+// BackfillTaskRanks gives every unranked task a rank within its status
+// column, preserving creation order. Columns that already contain ranked
+// tasks have the new ranks appended after the existing ones.
+func BackfillTaskRanks() error {
+	var columns []struct {
+		UserID uint
+		Status string
+	}
+	if err := DB.Model(&models.Task{}).
+		Select("DISTINCT user_id, status").
+		Where("rank = '' OR rank IS NULL").
+		Scan(&columns).Error; err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		var tasks []models.Task
+		if err := DB.Where("user_id = ? AND status = ? AND (rank = '' OR rank IS NULL)", column.UserID, column.Status).
+			Order("created_at asc, id asc").
+			Find(&tasks).Error; err != nil {
+			return err
+		}
+
+		var last []string
+		if err := DB.Model(&models.Task{}).
+			Where("user_id = ? AND status = ? AND rank <> ''", column.UserID, column.Status).
+			Order("rank desc").
+			Limit(1).
+			Pluck("rank", &last).Error; err != nil {
+			return err
+		}
+
+		tx := DB.Begin()
+		rank := ""
+		if len(last) > 0 {
+			rank = last[0]
+		}
+		for _, task := range tasks {
+			rank = ranking.After(rank)
+			if err := tx.Model(&task).UpdateColumn("rank", rank).Error; err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		if err := tx.Commit().Error; err != nil {
+			return err
+		}
+
+		log.Printf("Assigned ranks to %d tasks in column %q for user %d", len(tasks), column.Status, column.UserID)
+	}
+
+	return nil
+}
+
+// BackfillStatusTimestamps fills StartedAt and CompletedAt for tasks that
+// reached their status before those columns existed, using the last update
+// time as the best available estimate. It runs only when the columns are
+// first added. Statuses were fixed then, so the built-in in-progress and
+// completed names are the only ones such tasks can have.
+func BackfillStatusTimestamps() error {
+	if err := DB.Model(&models.Task{}).
+		Where("status = ? AND started_at IS NULL", "In-Progress").
+		UpdateColumn("started_at", gorm.Expr("updated_at")).Error; err != nil {
+		return err
+	}
+
+	return DB.Model(&models.Task{}).
+		Where("status = ? AND completed_at IS NULL", "Completed").
+		UpdateColumn("completed_at", gorm.Expr("updated_at")).Error
+}
+
+// SeedTaskDefinitions gives a user the default statuses and priorities, plus
+// one entry for every other value their tasks already use, so existing tasks
+// stay valid. Users who already have definitions are left untouched.
+// Rows another request seeded concurrently are skipped rather than
+// reported as conflicts.
+func SeedTaskDefinitions(db *gorm.DB, userID uint) error {
+	var count int
+	if err := db.Model(&models.TaskStatus{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		statuses := append([]models.TaskStatus(nil), models.DefaultTaskStatuses...)
+
+		var used []string
+		if err := db.Unscoped().Model(&models.Task{}).Where("user_id = ?", userID).Pluck("DISTINCT status", &used).Error; err != nil {
+			return err
+		}
+		for _, name := range used {
+			if !hasStatus(statuses, name) {
+				statuses = append(statuses, models.TaskStatus{Name: name, Position: len(statuses), Category: models.CategoryTodo})
+			}
+		}
+
+		for _, status := range statuses {
+			status.UserID = userID
+			if err := createIfAbsent(db, &status); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := db.Model(&models.TaskPriority{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		priorities := append([]models.TaskPriority(nil), models.DefaultTaskPriorities...)
+
+		var used []string
+		if err := db.Unscoped().Model(&models.Task{}).Where("user_id = ?", userID).Pluck("DISTINCT priority", &used).Error; err != nil {
+			return err
+		}
+		for _, name := range used {
+			if !hasPriority(priorities, name) {
+				priorities = append(priorities, models.TaskPriority{Name: name, Position: len(priorities)})
+			}
+		}
+
+		for _, priority := range priorities {
+			priority.UserID = userID
+			if err := createIfAbsent(db, &priority); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// SeedAllTaskDefinitions runs SeedTaskDefinitions for every user, migrating
+// the status and priority strings stored on existing tasks.
+func SeedAllTaskDefinitions() error {
+	var userIDs []uint
+	if err := DB.Model(&models.User{}).Pluck("id", &userIDs).Error; err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		tx := DB.Begin()
+		if err := SeedTaskDefinitions(tx, userID); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit().Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createIfAbsent inserts value unless it would violate a unique index. When
+// the row already exists, Postgres returns no ID and gorm reports
+// sql.ErrNoRows, which is expected here.
+func createIfAbsent(db *gorm.DB, value interface{}) error {
+	err := db.Set("gorm:insert_option", "ON CONFLICT DO NOTHING").Create(value).Error
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+func hasStatus(statuses []models.TaskStatus, name string) bool {
+	for _, status := range statuses {
+		if status.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPriority(priorities []models.TaskPriority, name string) bool {
+	for _, priority := range priorities {
+		if priority.Name == name {
+			return true
+		}
+	}
+	return false
+}
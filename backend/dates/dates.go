@@ -0,0 +1,98 @@
+// Package dates parses the date and time values clients send for due dates.
+//
+// A due date is either an all-day date ("2025-05-12"), which is pinned to
+// the start of that day in the user's timezone, or an exact instant. Instants
+// with an explicit offset are taken as given; those without one are read as
+// wall-clock time in the user's timezone.
+package dates
+
+import (
+	"errors"
+	"time"
+)
+
+const DateLayout = "2006-01-02"
+
+// ErrInvalid is returned for any value that isn't in one of the accepted
+// formats. Nothing is guessed: ambiguous forms like 05/12/2025 are rejected.
+var ErrInvalid = errors.New("Invalid date, expected YYYY-MM-DD, YYYY-MM-DDTHH:MM[:SS] or an RFC 3339 timestamp")
+
+var wallClockLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+}
+
+// Parse reads value in loc and reports whether it is an all-day date.
+func Parse(value string, loc *time.Location) (time.Time, bool, error) {
+	if t, err := ParseDay(value, loc); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, false, nil
+	}
+	for _, layout := range wallClockLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return WallClock(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), loc), false, nil
+		}
+	}
+	return time.Time{}, false, ErrInvalid
+}
+
+// ParseDay reads a YYYY-MM-DD date as the start of that day in loc.
+func ParseDay(value string, loc *time.Location) (time.Time, error) {
+	t, err := time.Parse(DateLayout, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return Day(t.Year(), t.Month(), t.Day(), loc), nil
+}
+
+// StartOfDay returns the start of t's calendar day in loc.
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	year, month, day := t.In(loc).Date()
+	return Day(year, month, day, loc)
+}
+
+// Day returns the start of a calendar day in loc. That is midnight, except
+// in zones that skip midnight when the clocks go forward, where the day
+// starts at the end of the gap. Out-of-range months and days are
+// normalized as by time.Date.
+func Day(year int, month time.Month, day int, loc *time.Location) time.Time {
+	return WallClock(year, month, day, 0, 0, 0, loc)
+}
+
+// AddDays returns the start of the day n calendar days after day, which
+// must itself be the start of a day. Unlike day.AddDate(0, 0, n) it stays
+// on a day boundary across daylight saving changes.
+func AddDays(day time.Time, n int) time.Time {
+	year, month, d := day.Date()
+	return Day(year, month, d+n, day.Location())
+}
+
+// WallClock returns the time in loc that clocks show as the given date and
+// time. A time skipped when the clocks go forward is moved forward by the
+// length of the gap, so 02:30 on a day that jumps from 02:00 to 03:00
+// becomes 03:30; time.Date would give 01:30, or the previous day for a
+// skipped midnight.
+func WallClock(year int, month time.Month, day, hour, min, sec int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, hour, min, sec, 0, loc)
+	want := time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+	got := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	if diff := want.Sub(got); diff > 0 {
+		t = t.Add(diff)
+	}
+	return t
+}
+
+// LoadLocation resolves an IANA timezone name, falling back to UTC for an
+// empty or unknown name so a bad stored value never breaks a request.
+func LoadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
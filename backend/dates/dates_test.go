@@ -0,0 +1,131 @@
+package dates
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skip("timezone data unavailable:", err)
+	}
+	return loc
+}
+
+func TestParse(t *testing.T) {
+	loc := mustLoad(t, "Europe/Berlin")
+
+	tests := []struct {
+		value  string
+		want   time.Time
+		allDay bool
+	}{
+		{"2025-05-12", time.Date(2025, 5, 12, 0, 0, 0, 0, loc), true},
+		{"2025-05-12T15:04", time.Date(2025, 5, 12, 15, 4, 0, 0, loc), false},
+		{"2025-05-12T15:04:05", time.Date(2025, 5, 12, 15, 4, 5, 0, loc), false},
+		{"2025-05-12T15:04:05Z", time.Date(2025, 5, 12, 15, 4, 5, 0, time.UTC), false},
+		{"2025-05-12T15:04:05.5-07:00", time.Date(2025, 5, 12, 22, 4, 5, 500000000, time.UTC), false},
+	}
+	for _, tt := range tests {
+		got, allDay, err := Parse(tt.value, loc)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) || allDay != tt.allDay {
+			t.Errorf("Parse(%q) = %v, %v, want %v, %v", tt.value, got, allDay, tt.want, tt.allDay)
+		}
+	}
+
+	for _, value := range []string{"", "05/12/2025", "2025-5-12", "2025-02-30", "2025-05-12 15:04", "tomorrow"} {
+		if _, _, err := Parse(value, loc); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalid", value, err)
+		}
+	}
+}
+
+// TestMidnightGap covers zones that move their clocks forward at midnight,
+// where time.Date normalizes a day's midnight into the day before.
+func TestMidnightGap(t *testing.T) {
+	loc := mustLoad(t, "America/Havana")
+	// Havana skips from 00:00 to 01:00 on 10 March 2024.
+	want := time.Date(2024, 3, 10, 1, 0, 0, 0, loc)
+
+	day, allDay, err := Parse("2024-03-10", loc)
+	if err != nil || !allDay || !day.Equal(want) {
+		t.Errorf("Parse = %v, %v, %v, want all-day %v", day, allDay, err, want)
+	}
+	if y, m, d := day.Date(); y != 2024 || m != time.March || d != 10 {
+		t.Errorf("Parse landed on %d-%02d-%02d", y, m, d)
+	}
+
+	if got := StartOfDay(time.Date(2024, 3, 10, 15, 0, 0, 0, loc), loc); !got.Equal(want) {
+		t.Errorf("StartOfDay = %v, want %v", got, want)
+	}
+	if got := AddDays(time.Date(2024, 3, 9, 0, 0, 0, 0, loc), 1); !got.Equal(want) {
+		t.Errorf("AddDays into the gap = %v, want %v", got, want)
+	}
+	if got, want := AddDays(want, 1), time.Date(2024, 3, 11, 0, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("AddDays out of the gap = %v, want %v", got, want)
+	}
+}
+
+func TestWallClockGap(t *testing.T) {
+	loc := mustLoad(t, "America/New_York")
+	// Clocks go forward from 02:00 to 03:00 on 9 March 2025.
+	got := WallClock(2025, time.March, 9, 2, 30, 0, loc)
+	if want := time.Date(2025, 3, 9, 3, 30, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("WallClock(02:30) = %v, want %v", got, want)
+	}
+
+	got, _, err := Parse("2025-03-09T02:30", loc)
+	if want := time.Date(2025, 3, 9, 3, 30, 0, 0, loc); err != nil || !got.Equal(want) {
+		t.Errorf("Parse(02:30) = %v, %v, want %v", got, err, want)
+	}
+
+	// Times outside the gap, including the repeated hour in November, are
+	// left as time.Date has them.
+	for _, tt := range []time.Time{
+		time.Date(2025, 3, 9, 1, 59, 0, 0, loc),
+		time.Date(2025, 3, 9, 3, 0, 0, 0, loc),
+		time.Date(2025, 11, 2, 1, 30, 0, 0, loc),
+	} {
+		if got := WallClock(tt.Year(), tt.Month(), tt.Day(), tt.Hour(), tt.Minute(), 0, loc); !got.Equal(tt) {
+			t.Errorf("WallClock(%v) = %v", tt, got)
+		}
+	}
+}
+
+func TestAddDaysAcrossDST(t *testing.T) {
+	loc := mustLoad(t, "Europe/Berlin")
+	day := time.Date(2025, 3, 28, 0, 0, 0, 0, loc)
+
+	for i := 0; i < 7; i++ {
+		day = AddDays(day, 1)
+		if day.Hour() != 0 || day.Minute() != 0 {
+			t.Fatalf("AddDays left midnight: %v", day)
+		}
+	}
+	if want := time.Date(2025, 4, 4, 0, 0, 0, 0, loc); !day.Equal(want) {
+		t.Errorf("day = %v, want %v", day, want)
+	}
+	if got, want := AddDays(day, -7), time.Date(2025, 3, 28, 0, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("AddDays(-7) = %v, want %v", got, want)
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	mustLoad(t, "Europe/Berlin")
+	for name, want := range map[string]string{
+		"":              "UTC",
+		"Not/AZone":     "UTC",
+		"Europe/Berlin": "Europe/Berlin",
+	} {
+		if got := LoadLocation(name).String(); got != want {
+			t.Errorf("LoadLocation(%q) = %s, want %s", name, got, want)
+		}
+	}
+}
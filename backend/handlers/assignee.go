@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/database"
+	"taskmanager/models"
+)
+
+type AssigneeRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+func isAssigned(taskID, userID uint) (bool, error) {
+	var count int
+	err := database.DB.Table("task_assignees").Where("task_id = ? AND user_id = ?", taskID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// loadAssignees fills in task's assignees for a response.
+func loadAssignees(task *models.Task) error {
+	return database.DB.Model(task).Related(&task.Assignees, "Assignees").Error
+}
+
+// AddAssignee assigns a user to a task. Only users who can see the task
+// can be assigned: its owner, members of its workspace and those it is
+// shared with. The user is notified; assigning them again changes nothing.
+func AddAssignee(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleEditor)
+	if err != nil {
+		respondAccessError(c, err, "Task not found")
+		return
+	}
+
+	var assigneeReq AssigneeRequest
+	if err := c.ShouldBindJSON(&assigneeReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	assignee, err := findUser(assigneeReq.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+	role, err := taskRole(assignee.ID, task)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if role == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The user doesn't have access to this task"})
+		return
+	}
+
+	assigned, err := isAssigned(task.ID, assignee.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !assigned {
+		if err := database.DB.Model(&task).Association("Assignees").Append(assignee.Summary()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := loadAssignees(&task); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !assigned {
+		notifyTaskEvent(assignee.ID, TaskEvent{Type: "task.assigned", TaskID: task.ID, Data: task})
+		notifyCollaborators(task)
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+// RemoveAssignee takes a user off a task. Editors can unassign anyone, and
+// assignees who can only view the task can still unassign themselves.
+func RemoveAssignee(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	assigneeID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assignee not found"})
+		return
+	}
+
+	required := models.RoleEditor
+	if uint(assigneeID) == userID {
+		required = models.RoleViewer
+	}
+	task, err := findTaskFor(userID, c.Param("id"), required)
+	if err != nil {
+		respondAccessError(c, err, "Task not found")
+		return
+	}
+
+	assignee, err := findUser(uint(assigneeID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assignee not found"})
+		return
+	}
+
+	assigned, err := isAssigned(task.ID, assignee.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !assigned {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assignee not found"})
+		return
+	}
+
+	if err := database.DB.Model(&task).Association("Assignees").Delete(assignee.Summary()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := loadAssignees(&task); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyTaskEvent(assignee.ID, TaskEvent{Type: "task.unassigned", TaskID: task.ID, Data: gin.H{"id": task.ID, "title": task.Title}})
+	notifyCollaborators(task)
+	c.JSON(http.StatusOK, task)
+}
+
+// GetAssignedTasks lists the tasks assigned to the user in every space,
+// their own, workspaces and tasks shared with them, a page at a time,
+// taking the same filters and sort order as GetTasks. Tasks they can no
+// longer see are left out.
+func GetAssignedTasks(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	assigned := database.DB.Model(&models.Task{}).
+		Where("tasks.id IN (SELECT task_id FROM task_assignees WHERE user_id = ?)", userID)
+	query, err := filterTasks(c, visibleTasks(assigned, userID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pagination := parsePagination(c)
+	if err := query.Count(&pagination.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tasks []models.Task
+	if err := query.Preload("Tags").Preload("Assignees").
+		Offset(pagination.Offset()).
+		Limit(pagination.PageSize).
+		Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tasks":      tasks,
+		"pagination": pagination,
+	})
+}
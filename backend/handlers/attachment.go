@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/database"
+	"taskmanager/models"
+	"taskmanager/storage"
+)
+
+const maxAttachmentSize = 10 << 20
+
+// allowedAttachmentTypes lists the MIME types accepted for upload. The type
+// is sniffed from the file contents; the client-supplied header is ignored.
+var allowedAttachmentTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"application/pdf",
+	"text/plain",
+	"text/csv",
+}
+
+func GetAttachments(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleViewer)
+	if err != nil {
+		respondAccessError(c, err, "Task not found")
+		return
+	}
+
+	var attachments []models.Attachment
+	if err := database.DB.Where("task_id = ?", task.ID).Order("created_at asc").Find(&attachments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+func UploadAttachment(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleEditor)
+	if err != nil {
+		respondAccessError(c, err, "Task not found")
+		return
+	}
+
+	// Leave headroom for the multipart envelope around the file itself.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAttachmentSize+1<<20)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File exceeds the 10 MB limit"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file is required in the 'file' form field"})
+		return
+	}
+	if fileHeader.Size > maxAttachmentSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File exceeds the 10 MB limit"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	tmp, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not buffer upload"})
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(file, maxAttachmentSize+1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not buffer upload"})
+		return
+	}
+	if size > maxAttachmentSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File exceeds the 10 MB limit"})
+		return
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	detected, err := mimetype.DetectReader(tmp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !mimetype.EqualsAny(detected.String(), allowedAttachmentTypes...) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "File type " + detected.String() + " is not allowed"})
+		return
+	}
+
+	checksum := hex.EncodeToString(hasher.Sum(nil))
+
+	// Hold the blob lock until the attachment row is committed, so a
+	// concurrent release can't delete the blob this upload is reusing.
+	tx := database.DB.Begin()
+
+	if err := lockBlob(tx, checksum); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing int
+	if err := tx.Model(&models.Attachment{}).Where("checksum = ?", checksum).Count(&existing).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if existing == 0 {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := storage.Blobs.Put(c.Request.Context(), checksum, tmp, size); err != nil {
+			tx.Rollback()
+			log.Printf("Error storing blob %s: %v", checksum, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not store file"})
+			return
+		}
+	}
+
+	attachment := models.Attachment{
+		TaskID:   task.ID,
+		UserID:   userID,
+		FileName: filepath.Base(fileHeader.Filename),
+		MimeType: detected.String(),
+		Size:     size,
+		Checksum: checksum,
+	}
+
+	if err := tx.Create(&attachment).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyCollaboratorsEvent(task, TaskEvent{Type: "task.attachment_added", TaskID: task.ID, Data: attachment})
+	c.JSON(http.StatusCreated, attachment)
+}
+
+func DownloadAttachment(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	_, attachment, err := findTaskAttachment(userID, c.Param("id"), c.Param("attachment_id"), models.RoleViewer)
+	if err != nil {
+		respondAccessError(c, err, "Attachment not found")
+		return
+	}
+
+	blob, err := storage.Blobs.Get(c.Request.Context(), attachment.Checksum)
+	if err != nil {
+		log.Printf("Error reading blob %s: %v", attachment.Checksum, err)
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment contents are missing"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not read file"})
+		}
+		return
+	}
+	defer blob.Close()
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.MimeType, blob, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+func DeleteAttachment(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, attachment, err := findTaskAttachment(userID, c.Param("id"), c.Param("attachment_id"), models.RoleEditor)
+	if err != nil {
+		respondAccessError(c, err, "Attachment not found")
+		return
+	}
+
+	if err := database.DB.Delete(&attachment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	releaseBlob(attachment.Checksum)
+
+	notifyCollaboratorsEvent(task, TaskEvent{Type: "task.attachment_deleted", TaskID: attachment.TaskID, Data: gin.H{"id": attachment.ID}})
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted successfully"})
+}
+
+// findTaskAttachment loads an attachment of a task userID holds at least
+// role on.
+func findTaskAttachment(userID uint, taskID, attachmentID, role string) (models.Task, models.Attachment, error) {
+	var attachment models.Attachment
+
+	task, err := findTaskFor(userID, taskID, role)
+	if err != nil {
+		return task, attachment, err
+	}
+
+	err = database.DB.Where("id = ? AND task_id = ?", attachmentID, task.ID).First(&attachment).Error
+	return task, attachment, err
+}
+
+// deleteTaskAttachments removes every attachment of a task and frees any
+// blobs that are no longer referenced by another attachment.
+func deleteTaskAttachments(taskID uint) error {
+	var attachments []models.Attachment
+	if err := database.DB.Where("task_id = ?", taskID).Find(&attachments).Error; err != nil {
+		return err
+	}
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	if err := database.DB.Where("task_id = ?", taskID).Delete(&models.Attachment{}).Error; err != nil {
+		return err
+	}
+
+	released := make(map[string]bool)
+	for _, attachment := range attachments {
+		if !released[attachment.Checksum] {
+			released[attachment.Checksum] = true
+			releaseBlob(attachment.Checksum)
+		}
+	}
+	return nil
+}
+
+// lockBlob takes a lock on the blob for checksum that lasts until tx ends.
+// Uploads and releases hold it across their reference count and the Put or
+// Delete that depends on it, so the two can't interleave.
+func lockBlob(tx *gorm.DB, checksum string) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "blob:"+checksum).Error
+}
+
+// releaseBlob deletes the stored blob for checksum once no attachment points
+// at it any more. Failures are logged rather than returned: an orphaned blob
+// only costs space, and the attachment rows are already gone.
+func releaseBlob(checksum string) {
+	tx := database.DB.Begin()
+	defer tx.Rollback()
+
+	if err := lockBlob(tx, checksum); err != nil {
+		log.Printf("Error locking blob %s: %v", checksum, err)
+		return
+	}
+
+	var remaining int
+	if err := tx.Model(&models.Attachment{}).Where("checksum = ?", checksum).Count(&remaining).Error; err != nil {
+		log.Printf("Error counting references to blob %s: %v", checksum, err)
+		return
+	}
+	if remaining > 0 {
+		return
+	}
+
+	if err := storage.Blobs.Delete(context.Background(), checksum); err != nil {
+		log.Printf("Error deleting blob %s: %v", checksum, err)
+	}
+}
@@ -113,8 +113,16 @@ func Login(c *gin.Context) {
 		Name:  user.Name,
 	}
 
+	// The client opens on the user's default view, if they have one.
+	defaultView, err := findDefaultView(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user":  userResponse,
+		"token":        token,
+		"user":         userResponse,
+		"default_view": defaultView,
 	})
 }
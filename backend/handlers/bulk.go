@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/database"
+	"taskmanager/models"
+)
+
+// BulkTaskRequest applies one action to many tasks at once.
+//
+//   - update: sets Status and/or Priority
+//   - delete: moves the tasks to the trash
+//   - move:   puts the tasks in ProjectID (null for no project)
+//   - tag:    adds AddTags and removes RemoveTags
+type BulkTaskRequest struct {
+	TaskIDs    []uint   `json:"task_ids" binding:"required,min=1,max=500"`
+	Action     string   `json:"action" binding:"required,oneof=update delete move tag"`
+	Status     string   `json:"status" binding:"omitempty,max=50"`
+	Priority   string   `json:"priority" binding:"omitempty,max=50"`
+	ProjectID  *uint    `json:"project_id"`
+	AddTags    []string `json:"add_tags" binding:"omitempty,max=20,dive,max=50"`
+	RemoveTags []string `json:"remove_tags" binding:"omitempty,max=20,dive,max=50"`
+}
+
+type BulkItemResult struct {
+	ID      uint   `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+var (
+	errBulkItemNotFound   = errors.New("Task not found or you don't have permission")
+	errInvalidBulkRequest = errors.New("Invalid bulk request")
+)
+
+// BulkTasks applies an action to a list of tasks of the active space in a
+// single transaction. Either every task is changed or, if any of them
+// fails, none are; the response reports the outcome for each ID either way.
+// In a workspace, members can change tasks and only admins delete them.
+func BulkTasks(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var bulkReq BulkTaskRequest
+	if err := c.ShouldBindJSON(&bulkReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	required := models.WorkspaceMember
+	if bulkReq.Action == "delete" {
+		required = models.WorkspaceAdmin
+	}
+	if !requireWorkspaceRole(c, required) {
+		return
+	}
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validateBulkRequest(space, bulkReq); err != nil {
+		if errors.Is(err, errInvalidBulkRequest) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			respondDefinitionError(c, err)
+		}
+		return
+	}
+
+	ids := uniqueIDs(bulkReq.TaskIDs)
+
+	tx := database.DB.Begin()
+
+	var tasks []models.Task
+	if err := space.scope(tx).Where("id IN (?)", ids).Find(&tasks).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	byID := make(map[uint]*models.Task, len(tasks))
+	for i := range tasks {
+		byID[tasks[i].ID] = &tasks[i]
+	}
+
+	var addTags, removeTags []models.Tag
+	if bulkReq.Action == "tag" {
+		var err error
+		if addTags, err = findOrCreateTags(tx, space.OwnerID, bulkReq.AddTags); err == nil {
+			removeTags, err = findOrCreateTags(tx, space.OwnerID, bulkReq.RemoveTags)
+		}
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	results := make([]BulkItemResult, 0, len(ids))
+	failed := false
+	for _, id := range ids {
+		task, ok := byID[id]
+		if !ok {
+			results = append(results, BulkItemResult{ID: id, Error: errBulkItemNotFound.Error()})
+			failed = true
+			continue
+		}
+
+		var err error
+		switch bulkReq.Action {
+		case "update":
+			err = bulkUpdateTask(tx, userID, task, bulkReq)
+		case "delete":
+			err = tx.Delete(task).Error
+		case "move":
+			err = bulkMoveTask(tx, userID, task, bulkReq.ProjectID)
+		case "tag":
+			err = bulkTagTask(tx, task, addTags, removeTags)
+		}
+
+		if err != nil {
+			results = append(results, BulkItemResult{ID: id, Error: err.Error()})
+			failed = true
+			continue
+		}
+		results = append(results, BulkItemResult{ID: id, Success: true})
+	}
+
+	if failed {
+		tx.Rollback()
+		for i := range results {
+			if results[i].Success {
+				results[i].Success = false
+				results[i].Error = "Not applied because other tasks failed"
+			}
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "No changes were applied",
+			"results": results,
+		})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, id := range space.users() {
+		notifyTaskEvent(id, TaskEvent{
+			Type: "tasks.bulk_updated",
+			Data: gin.H{"action": bulkReq.Action, "task_ids": ids},
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// validateBulkRequest checks the action's parameters once, before any task
+// is touched, so a bad status or project fails the whole request up front.
+func validateBulkRequest(space taskSpace, bulkReq BulkTaskRequest) error {
+	switch bulkReq.Action {
+	case "update":
+		if bulkReq.Status == "" && bulkReq.Priority == "" {
+			return fmt.Errorf("%w: update needs a status or priority", errInvalidBulkRequest)
+		}
+		if bulkReq.Status != "" {
+			if _, err := findStatus(space.OwnerID, bulkReq.Status); err != nil {
+				return err
+			}
+		}
+		if bulkReq.Priority != "" {
+			if err := validatePriority(space.OwnerID, bulkReq.Priority); err != nil {
+				return err
+			}
+		}
+	case "move":
+		if bulkReq.ProjectID != nil {
+			if _, err := findActiveProject(space, *bulkReq.ProjectID); err != nil {
+				return fmt.Errorf("%w: project not found or archived", errInvalidBulkRequest)
+			}
+		}
+	case "tag":
+		if len(bulkReq.AddTags) == 0 && len(bulkReq.RemoveTags) == 0 {
+			return fmt.Errorf("%w: tag needs add_tags or remove_tags", errInvalidBulkRequest)
+		}
+	}
+	return nil
+}
+
+func bulkUpdateTask(tx *gorm.DB, userID uint, task *models.Task, bulkReq BulkTaskRequest) error {
+	before := task.Snapshot()
+	if bulkReq.Status != "" && bulkReq.Status != task.Status {
+		rank, err := nextRankInColumn(tx, spaceOf(task.UserID, task.WorkspaceID), bulkReq.Status)
+		if err != nil {
+			return err
+		}
+		if err := changeTaskStatus(tx, task, bulkReq.Status, userID); err != nil {
+			return err
+		}
+		task.Rank = rank
+	}
+	if bulkReq.Priority != "" {
+		task.Priority = bulkReq.Priority
+	}
+	if err := tx.Save(task).Error; err != nil {
+		return err
+	}
+	return recordRevision(tx, userID, before, *task, nil)
+}
+
+func bulkMoveTask(tx *gorm.DB, userID uint, task *models.Task, projectID *uint) error {
+	before := task.Snapshot()
+	if err := tx.Model(task).Update("project_id", projectID).Error; err != nil {
+		return err
+	}
+	task.ProjectID = projectID
+	return recordRevision(tx, userID, before, *task, nil)
+}
+
+func bulkTagTask(tx *gorm.DB, task *models.Task, addTags, removeTags []models.Tag) error {
+	if len(addTags) > 0 {
+		if err := tx.Model(task).Association("Tags").Append(addTags).Error; err != nil {
+			return err
+		}
+	}
+	if len(removeTags) > 0 {
+		if err := tx.Model(task).Association("Tags").Delete(removeTags).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,932 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/database"
+	"taskmanager/ical"
+	"taskmanager/middleware"
+	"taskmanager/models"
+	"taskmanager/workflow"
+)
+
+// The CalDAV server exposes each user's tasks as a single VTODO calendar:
+//
+//	/caldav/                      service root
+//	/caldav/principals/me/        the authenticated user
+//	/caldav/calendars/            calendar home
+//	/caldav/calendars/tasks/      the task calendar
+//	/caldav/calendars/tasks/*.ics one task each
+//
+// Clients discover the calendar through PROPFIND, sync it with REPORT
+// (calendar-query and calendar-multiget) and ETags, and write tasks back
+// with PUT and DELETE. Writes go through the same workflow, revision history
+// and notifications as the REST handlers.
+
+const (
+	davNamespace    = "DAV:"
+	caldavNamespace = "urn:ietf:params:xml:ns:caldav"
+	csNamespace     = "http://calendarserver.org/ns/"
+
+	caldavRoot       = "/caldav/"
+	caldavPrincipal  = "/caldav/principals/me/"
+	caldavHome       = "/caldav/calendars/"
+	caldavCollection = "/caldav/calendars/tasks/"
+
+	caldavContentType = "text/calendar; charset=utf-8; component=vtodo"
+	maxCalendarObject = 1 << 20
+)
+
+var errCalendarObjectNotFound = errors.New("calendar object not found")
+
+// davNamespacePrefixes are the prefixes used in every response body.
+var davNamespacePrefixes = map[string]string{
+	davNamespace:    "d",
+	caldavNamespace: "c",
+	csNamespace:     "cs",
+}
+
+type davResponse struct {
+	href  string
+	found map[xml.Name]string
+}
+
+// davProps holds the properties a resource has, each as the XML inside its
+// property element.
+type davProps map[xml.Name]string
+
+func davName(space, local string) xml.Name {
+	return xml.Name{Space: space, Local: local}
+}
+
+func davHref(href string) string {
+	return "<d:href>" + xmlEscape(href) + "</d:href>"
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// etagFor is a hash of the task's rendered VTODO, so it changes with
+// anything the client sees, including tag, project and status or priority
+// renames that leave the task's own row untouched.
+func etagFor(cal *taskCalendar, task models.Task) string {
+	sum := sha256.Sum256(calendarData(cal, task))
+	return fmt.Sprintf(`"%d-%s"`, task.ID, hex.EncodeToString(sum[:12]))
+}
+
+// CreateAppPassword issues the password CalDAV clients sign in with, along
+// with the user's email, replacing any previous one. The password is only
+// ever shown in this response.
+func CreateAppPassword(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	user, err := findUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	password, hash, err := middleware.GenerateAppPassword()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", userID).Update("app_password_hash", hash).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"username": user.Email,
+		"password": password,
+		"url":      baseURL(c) + caldavRoot,
+	})
+}
+
+// RevokeAppPassword signs out every CalDAV client until a new app password
+// is issued.
+func RevokeAppPassword(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", userID).Update("app_password_hash", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "CalDAV app password revoked"})
+}
+
+// CalDAV handles every request under /caldav, dispatching on path and
+// method.
+func CalDAV(c *gin.Context) {
+	c.Header("DAV", "1, 3, calendar-access")
+
+	if c.Request.Method == http.MethodOptions {
+		c.Header("Allow", "OPTIONS, GET, PUT, DELETE, PROPFIND, REPORT")
+		c.Status(http.StatusOK)
+		return
+	}
+
+	href := path.Clean("/caldav/" + c.Param("path"))
+	if !strings.HasSuffix(href, ".ics") {
+		href += "/"
+	}
+
+	switch {
+	case href == caldavRoot || href == caldavPrincipal || href == caldavHome || href == caldavCollection:
+		switch c.Request.Method {
+		case "PROPFIND":
+			caldavPropfind(c, href)
+		case "REPORT":
+			if href != caldavCollection {
+				c.Status(http.StatusMethodNotAllowed)
+				return
+			}
+			caldavReport(c)
+		default:
+			c.Status(http.StatusMethodNotAllowed)
+		}
+	case path.Dir(href)+"/" == caldavCollection:
+		name := path.Base(href)
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead:
+			caldavGet(c, name)
+		case http.MethodPut:
+			caldavPut(c, name)
+		case http.MethodDelete:
+			caldavDelete(c, name)
+		case "PROPFIND":
+			caldavPropfind(c, href)
+		default:
+			c.Status(http.StatusMethodNotAllowed)
+		}
+	default:
+		c.Status(http.StatusNotFound)
+	}
+}
+
+// WellKnownCalDAV points clients doing service discovery at the root.
+func WellKnownCalDAV(c *gin.Context) {
+	c.Redirect(http.StatusMovedPermanently, caldavRoot)
+}
+
+// calendarObjectName is the resource name a task is served under.
+func calendarObjectName(task models.Task, names map[uint]string) string {
+	if name, ok := names[task.ID]; ok {
+		return name
+	}
+	return fmt.Sprintf("task-%d.ics", task.ID)
+}
+
+func calendarObjectNames(userID uint) (map[uint]string, error) {
+	var objects []models.CalendarObject
+	if err := database.DB.Where("user_id = ?", userID).Find(&objects).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(objects))
+	for _, object := range objects {
+		names[object.TaskID] = object.Name
+	}
+	return names, nil
+}
+
+// findCalendarTask resolves a resource name to a task of the user's
+// personal space, first by the names clients chose and then by the
+// task-<id>.ics form.
+func findCalendarTask(userID uint, name string) (models.Task, error) {
+	var task models.Task
+
+	var object models.CalendarObject
+	err := database.DB.Where("user_id = ? AND name = ?", userID, name).First(&object).Error
+	if err == nil {
+		if err := calendarSpace(userID).scope(database.DB).Preload("Tags").Where("id = ?", object.TaskID).First(&task).Error; err != nil {
+			return task, errCalendarObjectNotFound
+		}
+		return task, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return task, err
+	}
+
+	var id uint
+	if _, err := fmt.Sscanf(name, "task-%d.ics", &id); err != nil || fmt.Sprintf("task-%d.ics", id) != name {
+		return task, errCalendarObjectNotFound
+	}
+	if err := calendarSpace(userID).scope(database.DB).Preload("Tags").Where("id = ?", id).First(&task).Error; err != nil {
+		return task, errCalendarObjectNotFound
+	}
+	return task, nil
+}
+
+// collectionCTag changes whenever any task in the calendar is added,
+// changed or deleted. It is a hash of every task's ETag.
+func collectionCTag(userID uint) (string, error) {
+	cal, err := newTaskCalendar(userID)
+	if err != nil {
+		return "", err
+	}
+	tasks, _, err := calendarTasks(userID)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	for _, task := range tasks {
+		io.WriteString(hash, etagFor(cal, task))
+	}
+	return fmt.Sprintf(`"%s"`, hex.EncodeToString(hash.Sum(nil)[:16])), nil
+}
+
+// collectionProps are the properties of the fixed resources: the root,
+// principal, home and task calendar.
+func collectionProps(userID uint, href string) (davProps, error) {
+	props := davProps{
+		davName(davNamespace, "current-user-principal"): davHref(caldavPrincipal),
+		davName(davNamespace, "principal-URL"):          davHref(caldavPrincipal),
+		davName(caldavNamespace, "calendar-home-set"):   davHref(caldavHome),
+		davName(davNamespace, "owner"):                  davHref(caldavPrincipal),
+	}
+
+	switch href {
+	case caldavPrincipal:
+		props[davName(davNamespace, "resourcetype")] = "<d:principal/><d:collection/>"
+		props[davName(davNamespace, "displayname")] = "Me"
+		props[davName(caldavNamespace, "calendar-user-address-set")] = ""
+		if user, err := findUser(userID); err == nil {
+			props[davName(davNamespace, "displayname")] = xmlEscape(user.Name)
+			props[davName(caldavNamespace, "calendar-user-address-set")] = davHref("mailto:" + user.Email)
+		}
+	case caldavCollection:
+		ctag, err := collectionCTag(userID)
+		if err != nil {
+			return nil, err
+		}
+		props[davName(davNamespace, "resourcetype")] = "<d:collection/><c:calendar/>"
+		props[davName(davNamespace, "displayname")] = "Tasks"
+		props[davName(caldavNamespace, "supported-calendar-component-set")] = `<c:comp name="VTODO"/>`
+		props[davName(csNamespace, "getctag")] = xmlEscape(ctag)
+		props[davName(davNamespace, "getetag")] = xmlEscape(ctag)
+		props[davName(davNamespace, "current-user-privilege-set")] =
+			"<d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege>"
+	default:
+		props[davName(davNamespace, "resourcetype")] = "<d:collection/>"
+	}
+	return props, nil
+}
+
+func objectProps(cal *taskCalendar, task models.Task) davProps {
+	return davProps{
+		davName(davNamespace, "resourcetype"):   "",
+		davName(davNamespace, "getetag"):        xmlEscape(etagFor(cal, task)),
+		davName(davNamespace, "getcontenttype"): caldavContentType,
+	}
+}
+
+// readPropRequest returns the property names asked for by a PROPFIND or
+// REPORT body, or nil for allprop (including an empty body).
+func readPropRequest(body []byte) ([]xml.Name, xml.Name, error) {
+	var requested []xml.Name
+	var root xml.Name
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	depth := 0
+	inProp := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, root, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				root = t.Name
+			}
+			if inProp && depth == 3 {
+				requested = append(requested, t.Name)
+			}
+			if depth == 2 && t.Name.Space == davNamespace && t.Name.Local == "prop" {
+				inProp = true
+			}
+		case xml.EndElement:
+			if depth == 2 {
+				inProp = false
+			}
+			depth--
+		}
+	}
+	return requested, root, nil
+}
+
+// writeMultistatus renders responses, reporting each requested property in
+// a 200 propstat if the resource has it and a 404 propstat if not. With no
+// requested properties every property is returned.
+func writeMultistatus(c *gin.Context, responses []davResponse, requested []xml.Name) {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	buf.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">`)
+	for _, response := range responses {
+		buf.WriteString("<d:response>" + davHref(response.href))
+
+		var found, missing bytes.Buffer
+		names := requested
+		if names == nil {
+			for name := range response.found {
+				names = append(names, name)
+			}
+		}
+		for _, name := range names {
+			value, ok := response.found[name]
+			prefix, known := davNamespacePrefixes[name.Space]
+			element := prefix + ":" + name.Local
+			open := "<" + element + ">"
+			if !known {
+				element = "x:" + name.Local
+				open = `<x:` + name.Local + ` xmlns:x="` + xmlEscape(name.Space) + `">`
+			}
+			if ok {
+				found.WriteString(open + value + "</" + element + ">")
+			} else {
+				missing.WriteString(strings.TrimSuffix(open, ">") + "/>")
+			}
+		}
+		if found.Len() > 0 || missing.Len() == 0 {
+			buf.WriteString("<d:propstat><d:prop>" + found.String() + "</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>")
+		}
+		if missing.Len() > 0 {
+			buf.WriteString("<d:propstat><d:prop>" + missing.String() + "</d:prop><d:status>HTTP/1.1 404 Not Found</d:status></d:propstat>")
+		}
+		buf.WriteString("</d:response>")
+	}
+	buf.WriteString("</d:multistatus>")
+
+	c.Data(http.StatusMultiStatus, "application/xml; charset=utf-8", buf.Bytes())
+}
+
+func caldavPropfind(c *gin.Context, href string) {
+	userID := c.GetUint("userID")
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCalendarObject))
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	requested, _, err := readPropRequest(body)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	depth := c.GetHeader("Depth")
+
+	if strings.HasSuffix(href, ".ics") {
+		task, err := findCalendarTask(userID, path.Base(href))
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		cal, err := newTaskCalendar(userID)
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		writeMultistatus(c, []davResponse{{href: href, found: objectProps(cal, task)}}, requested)
+		return
+	}
+
+	props, err := collectionProps(userID, href)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	responses := []davResponse{{href: href, found: props}}
+
+	if depth != "0" {
+		switch href {
+		case caldavRoot:
+			principal, _ := collectionProps(userID, caldavPrincipal)
+			responses = append(responses, davResponse{href: caldavPrincipal, found: principal})
+		case caldavHome:
+			collection, err := collectionProps(userID, caldavCollection)
+			if err != nil {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			responses = append(responses, davResponse{href: caldavCollection, found: collection})
+		case caldavCollection:
+			cal, err := newTaskCalendar(userID)
+			if err != nil {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			tasks, names, err := calendarTasks(userID)
+			if err != nil {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			for _, task := range tasks {
+				name := calendarObjectName(task, names)
+				responses = append(responses, davResponse{href: caldavCollection + name, found: objectProps(cal, task)})
+			}
+		}
+	}
+
+	writeMultistatus(c, responses, requested)
+}
+
+// calendarSpace is the space the user's calendar holds: their personal
+// tasks. Workspace tasks they own as the workspace's owner are left out,
+// as they are from the personal task list.
+func calendarSpace(userID uint) taskSpace {
+	return spaceOf(userID, nil)
+}
+
+func calendarTasks(userID uint) ([]models.Task, map[uint]string, error) {
+	var tasks []models.Task
+	if err := calendarSpace(userID).scope(database.DB).Preload("Tags").Order("id asc").Find(&tasks).Error; err != nil {
+		return nil, nil, err
+	}
+	names, err := calendarObjectNames(userID)
+	return tasks, names, err
+}
+
+// calendarData renders a task as a complete iCalendar object.
+func calendarData(cal *taskCalendar, task models.Task) []byte {
+	var buf bytes.Buffer
+	object := ical.Calendar{ProdID: calendarProdID, Components: []ical.Component{cal.Todo(task)}}
+	object.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// reportRequest is the part of a calendar-query or calendar-multiget body
+// the server acts on.
+type reportRequest struct {
+	Hrefs      []string `xml:"DAV: href"`
+	CompFilter []struct {
+		Name       string `xml:"name,attr"`
+		CompFilter []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	} `xml:"urn:ietf:params:xml:ns:caldav filter>comp-filter"`
+}
+
+// caldavReport answers calendar-multiget with the named tasks and
+// calendar-query with every task. Only the component type of a query's
+// filter is honoured; clients filter the rest themselves.
+func caldavReport(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCalendarObject))
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	requested, root, err := readPropRequest(body)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	var report reportRequest
+	if err := xml.Unmarshal(body, &report); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	cal, err := newTaskCalendar(userID)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	tasks, names, err := calendarTasks(userID)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	byHref := make(map[string]models.Task, len(tasks))
+	for _, task := range tasks {
+		byHref[caldavCollection+calendarObjectName(task, names)] = task
+	}
+
+	var responses []davResponse
+	switch {
+	case root.Space == caldavNamespace && root.Local == "calendar-multiget":
+		for _, raw := range report.Hrefs {
+			href := raw
+			if parsed, err := url.Parse(raw); err == nil {
+				href = parsed.Path
+			}
+			task, ok := byHref[href]
+			if !ok {
+				responses = append(responses, davResponse{href: href, found: davProps{}})
+				continue
+			}
+			responses = append(responses, davResponse{href: href, found: reportProps(cal, task)})
+		}
+	case root.Space == caldavNamespace && root.Local == "calendar-query":
+		if !queriesTodos(report) {
+			break
+		}
+		for _, task := range tasks {
+			responses = append(responses, davResponse{
+				href:  caldavCollection + calendarObjectName(task, names),
+				found: reportProps(cal, task),
+			})
+		}
+	default:
+		c.Status(http.StatusNotImplemented)
+		return
+	}
+
+	writeMultistatus(c, responses, requested)
+}
+
+func queriesTodos(report reportRequest) bool {
+	for _, calendarFilter := range report.CompFilter {
+		for _, filter := range calendarFilter.CompFilter {
+			if !strings.EqualFold(filter.Name, "VTODO") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func reportProps(cal *taskCalendar, task models.Task) davProps {
+	props := objectProps(cal, task)
+	var data bytes.Buffer
+	xml.EscapeText(&data, calendarData(cal, task))
+	props[davName(caldavNamespace, "calendar-data")] = data.String()
+	return props
+}
+
+func caldavGet(c *gin.Context, name string) {
+	userID := c.GetUint("userID")
+
+	task, err := findCalendarTask(userID, name)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	cal, err := newTaskCalendar(userID)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("ETag", etagFor(cal, task))
+	c.Data(http.StatusOK, caldavContentType, calendarData(cal, task))
+}
+
+// preconditionFailed applies If-Match and If-None-Match to a resource that
+// may not exist (etag is empty then).
+func preconditionFailed(c *gin.Context, etag string) bool {
+	if match := c.GetHeader("If-Match"); match != "" {
+		if etag == "" || (match != "*" && match != etag) {
+			return true
+		}
+	}
+	if noneMatch := c.GetHeader("If-None-Match"); noneMatch != "" {
+		if etag != "" && (noneMatch == "*" || noneMatch == etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// caldavPut creates or replaces a task from a VTODO.
+func caldavPut(c *gin.Context, name string) {
+	userID := c.GetUint("userID")
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCalendarObject))
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	object, err := ical.Parse(bytes.NewReader(body))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	var todo *ical.Component
+	for i := range object.Components {
+		if object.Components[i].Name == "VTODO" {
+			todo = &object.Components[i]
+			break
+		}
+	}
+	if todo == nil {
+		c.String(http.StatusForbidden, "Only VTODO components can be stored in this calendar")
+		return
+	}
+
+	task, err := findCalendarTask(userID, name)
+	exists := err == nil
+	if err != nil && !errors.Is(err, errCalendarObjectNotFound) {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	cal, err := newTaskCalendar(userID)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	etag := ""
+	if exists {
+		etag = etagFor(cal, task)
+	}
+	if preconditionFailed(c, etag) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	if exists {
+		err = updateTaskFromTodo(cal, userID, &task, *todo)
+	} else {
+		task, err = createTaskFromTodo(cal, userID, name, *todo)
+	}
+	if err != nil {
+		var transitionErr *workflow.TransitionError
+		if errors.As(err, &transitionErr) {
+			c.String(http.StatusConflict, transitionErr.Error())
+			return
+		}
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	// Reload the task and the definitions the VTODO may have added to, so
+	// the ETag matches what later requests will compute.
+	if err := database.DB.Preload("Tags").First(&task, task.ID).Error; err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	if cal, err = newTaskCalendar(userID); err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	notifyCollaborators(task)
+	c.Header("ETag", etagFor(cal, task))
+	if exists {
+		c.Status(http.StatusNoContent)
+	} else {
+		c.Status(http.StatusCreated)
+	}
+}
+
+func caldavDelete(c *gin.Context, name string) {
+	userID := c.GetUint("userID")
+
+	task, err := findCalendarTask(userID, name)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	cal, err := newTaskCalendar(userID)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	if preconditionFailed(c, etagFor(cal, task)) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	// Like DELETE /tasks/:id, this only moves the task to the trash.
+	if err := database.DB.Delete(&task).Error; err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	notifyCollaborators(task)
+	c.Status(http.StatusNoContent)
+}
+
+// todoChanges is what a VTODO says about a task, read against the user's
+// definitions.
+type todoChanges struct {
+	status   string
+	priority string
+	tags     []string
+	setTags  bool
+}
+
+// applyTodo copies the VTODO's fields onto task and works out the status,
+// priority and tags it asks for. STATUS picks the first status of the
+// matching category unless the task is already in one; PRIORITY picks the
+// nearest of the user's priorities. CATEGORIES other than the task's
+// project become its tags, but only when the client sends them, so clients
+// without categories don't wipe tags.
+func (cal *taskCalendar) applyTodo(task *models.Task, todo ical.Component) todoChanges {
+	changes := todoChanges{status: task.Status, priority: task.Priority}
+
+	task.Title = "Untitled"
+	if p, ok := todo.Get("SUMMARY"); ok && strings.TrimSpace(ical.Unescape(p.Value)) != "" {
+		task.Title = strings.TrimSpace(ical.Unescape(p.Value))
+	}
+	task.Description = ""
+	if p, ok := todo.Get("DESCRIPTION"); ok {
+		task.Description = ical.Unescape(p.Value)
+	}
+
+	task.DueDate, task.DueAllDay = nil, false
+	if p, ok := todo.Get("DUE"); ok {
+		if due, allDay, err := ical.ParseTime(p, cal.loc); err == nil {
+			task.DueDate, task.DueAllDay = &due, allDay
+		}
+	}
+
+	category := models.CategoryTodo
+	if p, ok := todo.Get("STATUS"); ok {
+		switch strings.ToUpper(p.Value) {
+		case "IN-PROCESS":
+			category = models.CategoryInProgress
+		case "COMPLETED", "CANCELLED":
+			category = models.CategoryDone
+		}
+	} else if _, ok := todo.Get("COMPLETED"); ok {
+		category = models.CategoryDone
+	}
+	if cal.categories[task.Status] != category {
+		for _, status := range cal.statuses {
+			if status.Category == category {
+				changes.status = status.Name
+				break
+			}
+		}
+	}
+
+	if p, ok := todo.Get("PRIORITY"); ok {
+		if level, err := strconv.Atoi(strings.TrimSpace(p.Value)); err == nil && level >= 1 && level <= 9 {
+			best := -1
+			for _, priority := range cal.priorities {
+				distance := cal.levels[priority.Name] - level
+				if distance < 0 {
+					distance = -distance
+				}
+				if best < 0 || distance < best {
+					best = distance
+					changes.priority = priority.Name
+				}
+			}
+		}
+	}
+	if changes.priority == "" && len(cal.priorities) > 0 {
+		changes.priority = cal.priorities[0].Name
+	}
+	task.Priority = changes.priority
+
+	projectName := ""
+	if task.ProjectID != nil {
+		projectName = cal.projectNames[*task.ProjectID]
+	}
+	for _, p := range todo.All("CATEGORIES") {
+		changes.setTags = true
+		for _, category := range ical.SplitList(p.Value) {
+			if category != "" && !strings.EqualFold(category, projectName) {
+				changes.tags = append(changes.tags, category)
+			}
+		}
+	}
+	return changes
+}
+
+func updateTaskFromTodo(cal *taskCalendar, userID uint, task *models.Task, todo ical.Component) error {
+	before := task.Snapshot()
+	changes := cal.applyTodo(task, todo)
+
+	if changes.status != task.Status {
+		rank, err := nextRankInColumn(database.DB, calendarSpace(userID), changes.status)
+		if err != nil {
+			return err
+		}
+		task.Rank = rank
+	}
+
+	tx := database.DB.Begin()
+	notifications, err := saveTaskFromTodo(tx, userID, task, changes, before)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	pushNotifications(notifications)
+	return nil
+}
+
+func createTaskFromTodo(cal *taskCalendar, userID uint, name string, todo ical.Component) (models.Task, error) {
+	var task models.Task
+	calendarSpace(userID).place(&task)
+	changes := cal.applyTodo(&task, todo)
+	if changes.status == "" && len(cal.statuses) > 0 {
+		changes.status = cal.statuses[0].Name
+	}
+
+	status, err := findStatus(userID, changes.status)
+	if err != nil {
+		return task, err
+	}
+	if _, err := workflow.Apply(&task, models.TaskStatus{}, status, time.Now()); err != nil {
+		return task, err
+	}
+	if p, ok := todo.Get("COMPLETED"); ok && status.IsDone() {
+		if completed, _, err := ical.ParseTime(p, cal.loc); err == nil {
+			task.CompletedAt = &completed
+		}
+	}
+
+	task.Rank, err = nextRankInColumn(database.DB, calendarSpace(userID), task.Status)
+	if err != nil {
+		return task, err
+	}
+
+	uid := strings.TrimSuffix(name, ".ics")
+	if p, ok := todo.Get("UID"); ok && p.Value != "" {
+		uid = p.Value
+	}
+
+	tx := database.DB.Begin()
+
+	if err := tx.Create(&task).Error; err != nil {
+		tx.Rollback()
+		return task, err
+	}
+	if err := recordStatusChange(tx, task.ID, userID, "", task.Status); err != nil {
+		tx.Rollback()
+		return task, err
+	}
+	// A name left behind by a task in the trash goes to the new task.
+	if err := tx.Where("user_id = ? AND name = ?", userID, name).Delete(&models.CalendarObject{}).Error; err != nil {
+		tx.Rollback()
+		return task, err
+	}
+	object := models.CalendarObject{UserID: userID, TaskID: task.ID, Name: name, UID: uid}
+	if err := tx.Create(&object).Error; err != nil {
+		tx.Rollback()
+		return task, err
+	}
+	notifications, err := saveTaskFromTodo(tx, userID, &task, changes, models.TaskSnapshot{})
+	if err != nil {
+		tx.Rollback()
+		return task, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return task, err
+	}
+	pushNotifications(notifications)
+	return task, nil
+}
+
+// saveTaskFromTodo finishes a CalDAV write inside tx: the status change,
+// the task itself, its tags, the revision and the description's mentions.
+// It returns the mention notifications to push once tx commits.
+func saveTaskFromTodo(tx *gorm.DB, userID uint, task *models.Task, changes todoChanges, before models.TaskSnapshot) ([]models.Notification, error) {
+	if err := changeTaskStatus(tx, task, changes.status, userID); err != nil {
+		return nil, err
+	}
+	if err := tx.Save(task).Error; err != nil {
+		return nil, err
+	}
+	if changes.setTags {
+		tags, err := findOrCreateTags(tx, userID, changes.tags)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Model(task).Association("Tags").Replace(tags).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := recordRevision(tx, userID, before, *task, nil); err != nil {
+		return nil, err
+	}
+	if task.Description == before.Description {
+		return nil, nil
+	}
+	return syncMentions(tx, *task, nil, userID, task.Description)
+}
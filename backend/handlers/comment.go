@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/database"
+	"taskmanager/models"
+)
+
+type CommentRequest struct {
+	Body string `json:"body" binding:"required,max=10000"`
+}
+
+func GetComments(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleViewer)
+	if err != nil {
+		respondAccessError(c, err, "Task not found")
+		return
+	}
+
+	pagination := parsePagination(c)
+	query := database.DB.Model(&models.Comment{}).Where("task_id = ?", task.ID)
+
+	if err := query.Count(&pagination.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var comments []models.Comment
+	if err := query.Order("created_at asc").Offset(pagination.Offset()).Limit(pagination.PageSize).Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comments":   comments,
+		"pagination": pagination,
+	})
+}
+
+func CreateComment(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleEditor)
+	if err != nil {
+		respondAccessError(c, err, "Task not found")
+		return
+	}
+
+	var commentReq CommentRequest
+	if err := c.ShouldBindJSON(&commentReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment := models.Comment{
+		TaskID: task.ID,
+		UserID: userID,
+		Body:   commentReq.Body,
+	}
+
+	tx := database.DB.Begin()
+
+	if err := tx.Create(&comment).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifications, err := syncMentions(tx, task, &comment.ID, userID, comment.Body)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pushNotifications(notifications)
+	notifyCollaboratorsEvent(task, TaskEvent{Type: "task.comment_added", TaskID: task.ID, Data: comment})
+	c.JSON(http.StatusCreated, comment)
+}
+
+func UpdateComment(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleViewer)
+	if err != nil {
+		respondAccessError(c, err, "Task not found")
+		return
+	}
+
+	comment, err := findOwnComment(userID, c.Param("id"), c.Param("comment_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found or you don't have permission"})
+		return
+	}
+
+	var commentReq CommentRequest
+	if err := c.ShouldBindJSON(&commentReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if commentReq.Body == comment.Body {
+		c.JSON(http.StatusOK, comment)
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	revision := models.CommentRevision{
+		CommentID: comment.ID,
+		Body:      comment.Body,
+	}
+	if err := tx.Create(&revision).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	comment.Body = commentReq.Body
+	comment.EditedAt = &now
+	if err := tx.Save(&comment).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifications, err := syncMentions(tx, task, &comment.ID, userID, comment.Body)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pushNotifications(notifications)
+	notifyCollaboratorsEvent(task, TaskEvent{Type: "task.comment_updated", TaskID: comment.TaskID, Data: comment})
+	c.JSON(http.StatusOK, comment)
+}
+
+func DeleteComment(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleViewer)
+	if err != nil {
+		respondAccessError(c, err, "Task not found")
+		return
+	}
+
+	comment, err := findOwnComment(userID, c.Param("id"), c.Param("comment_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found or you don't have permission"})
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	if err := tx.Delete(&comment).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Where("comment_id = ?", comment.ID).Delete(&models.Mention{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyCollaboratorsEvent(task, TaskEvent{Type: "task.comment_deleted", TaskID: comment.TaskID, Data: gin.H{"id": comment.ID}})
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+func GetCommentHistory(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleViewer)
+	if err != nil {
+		respondAccessError(c, err, "Task not found")
+		return
+	}
+
+	var comment models.Comment
+	if err := database.DB.Where("id = ? AND task_id = ?", c.Param("comment_id"), task.ID).First(&comment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	var revisions []models.CommentRevision
+	if err := database.DB.Where("comment_id = ?", comment.ID).Order("created_at desc").Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comment":   comment,
+		"revisions": revisions,
+	})
+}
+
+// findOwnComment loads a comment on the given task that was written by userID.
+// Only the author may edit or delete a comment, and only while they can
+// still see the task.
+func findOwnComment(userID uint, taskID, commentID string) (models.Comment, error) {
+	var comment models.Comment
+	err := database.DB.Where("id = ? AND task_id = ? AND user_id = ?", commentID, taskID, userID).First(&comment).Error
+	return comment, err
+}
@@ -0,0 +1,604 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/database"
+	"taskmanager/models"
+	"taskmanager/workflow"
+)
+
+var (
+	errUnknownStatus   = errors.New("Unknown status")
+	errUnknownPriority = errors.New("Unknown priority")
+)
+
+type StatusRequest struct {
+	Name     string `json:"name" binding:"required,max=50"`
+	Color    string `json:"color" binding:"omitempty,hexcolor"`
+	Position int    `json:"position"`
+	Category string `json:"category" binding:"required,oneof=todo in_progress done"`
+}
+
+type PriorityRequest struct {
+	Name     string `json:"name" binding:"required,max=50"`
+	Color    string `json:"color" binding:"omitempty,hexcolor"`
+	Position int    `json:"position"`
+}
+
+// userStatuses returns the user's statuses in board order, giving them the
+// defaults first if they have none yet.
+func userStatuses(userID uint) ([]models.TaskStatus, error) {
+	var statuses []models.TaskStatus
+	if err := database.DB.Where("user_id = ?", userID).Order("position asc, id asc").Find(&statuses).Error; err != nil {
+		return nil, err
+	}
+	if len(statuses) > 0 {
+		return statuses, nil
+	}
+
+	if err := database.SeedTaskDefinitions(database.DB, userID); err != nil {
+		return nil, err
+	}
+	err := database.DB.Where("user_id = ?", userID).Order("position asc, id asc").Find(&statuses).Error
+	return statuses, err
+}
+
+// userPriorities returns the user's priorities from least to most urgent,
+// giving them the defaults first if they have none yet.
+func userPriorities(userID uint) ([]models.TaskPriority, error) {
+	var priorities []models.TaskPriority
+	if err := database.DB.Where("user_id = ?", userID).Order("position asc, id asc").Find(&priorities).Error; err != nil {
+		return nil, err
+	}
+	if len(priorities) > 0 {
+		return priorities, nil
+	}
+
+	if err := database.SeedTaskDefinitions(database.DB, userID); err != nil {
+		return nil, err
+	}
+	err := database.DB.Where("user_id = ?", userID).Order("position asc, id asc").Find(&priorities).Error
+	return priorities, err
+}
+
+func findStatus(userID uint, name string) (models.TaskStatus, error) {
+	statuses, err := userStatuses(userID)
+	if err != nil {
+		return models.TaskStatus{}, err
+	}
+	for _, status := range statuses {
+		if status.Name == name {
+			return status, nil
+		}
+	}
+	return models.TaskStatus{}, fmt.Errorf("%w %q", errUnknownStatus, name)
+}
+
+func validatePriority(userID uint, name string) error {
+	priorities, err := userPriorities(userID)
+	if err != nil {
+		return err
+	}
+	for _, priority := range priorities {
+		if priority.Name == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %q", errUnknownPriority, name)
+}
+
+// respondDefinitionError reports a failed status or priority lookup, telling
+// unknown names (the client's fault) apart from database errors.
+func respondDefinitionError(c *gin.Context, err error) {
+	if errors.Is(err, errUnknownStatus) || errors.Is(err, errUnknownPriority) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// GetStatuses lists the statuses of the active space. A workspace uses its
+// owner's statuses and priorities, so only the owner can change them there.
+func GetStatuses(c *gin.Context) {
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	statuses, err := userStatuses(space.OwnerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, statuses)
+}
+
+func CreateStatus(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	if !requireWorkspaceRole(c, models.WorkspaceOwner) {
+		return
+	}
+
+	var statusReq StatusRequest
+	if err := c.ShouldBindJSON(&statusReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := findStatus(userID, statusReq.Name); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A status with this name already exists"})
+		return
+	} else if !errors.Is(err, errUnknownStatus) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := models.TaskStatus{
+		UserID:   userID,
+		Name:     statusReq.Name,
+		Color:    statusReq.Color,
+		Position: statusReq.Position,
+		Category: statusReq.Category,
+	}
+
+	if err := database.DB.Create(&status).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyTaskUpdate(userID)
+	c.JSON(http.StatusCreated, status)
+}
+
+// UpdateStatus edits a status. Renaming it renames the status on every task
+// and saved view that uses it, and moving it to another category brings
+// those tasks' StartedAt and CompletedAt in line with the new category.
+func UpdateStatus(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	if !requireWorkspaceRole(c, models.WorkspaceOwner) {
+		return
+	}
+
+	var status models.TaskStatus
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&status).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Status not found"})
+		return
+	}
+
+	var statusReq StatusRequest
+	if err := c.ShouldBindJSON(&statusReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	oldName, oldCategory := status.Name, status.Category
+	if statusReq.Name != oldName {
+		if _, err := findStatus(userID, statusReq.Name); err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "A status with this name already exists"})
+			return
+		}
+	}
+
+	status.Name = statusReq.Name
+	status.Color = statusReq.Color
+	status.Position = statusReq.Position
+	status.Category = statusReq.Category
+
+	tx := database.DB.Begin()
+
+	if err := tx.Save(&status).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if status.Name != oldName {
+		if err := tx.Unscoped().Model(&models.Task{}).
+			Where("user_id = ? AND status = ?", userID, oldName).
+			UpdateColumn("status", status.Name).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := renameViewFilter(tx, userID, "status", oldName, status.Name); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if status.Category != oldCategory {
+		if err := syncStatusTimestamps(tx, userID, status); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyTaskUpdate(userID)
+	c.JSON(http.StatusOK, status)
+}
+
+// syncStatusTimestamps reapplies status to every task in it, as if the tasks
+// had just entered it, after its category changed. The change isn't a
+// transition of the tasks themselves, so the workflow rules don't apply.
+func syncStatusTimestamps(tx *gorm.DB, userID uint, status models.TaskStatus) error {
+	var tasks []models.Task
+	if err := tx.Unscoped().Where("user_id = ? AND status = ?", userID, status.Name).Find(&tasks).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, task := range tasks {
+		if _, err := workflow.Apply(&task, models.TaskStatus{}, status, now); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&task).UpdateColumns(map[string]interface{}{
+			"started_at":   task.StartedAt,
+			"completed_at": task.CompletedAt,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteStatus removes a status. If tasks still use it, the replace_with
+// query parameter must name another status to move them to; they go to the
+// bottom of its column. Saved views filtering on the status follow
+// replace_with or stop filtering on status.
+func DeleteStatus(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	if !requireWorkspaceRole(c, models.WorkspaceOwner) {
+		return
+	}
+
+	var status models.TaskStatus
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&status).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Status not found"})
+		return
+	}
+
+	var remaining int
+	if err := database.DB.Model(&models.TaskStatus{}).Where("user_id = ? AND id <> ?", userID, status.ID).Count(&remaining).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if remaining == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You must keep at least one status"})
+		return
+	}
+
+	replacement := c.Query("replace_with")
+	var replacementStatus models.TaskStatus
+	if replacement != "" {
+		if replacement == status.Name {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "replace_with must name a different status"})
+			return
+		}
+		found, err := findStatus(userID, replacement)
+		if err != nil {
+			respondDefinitionError(c, err)
+			return
+		}
+		replacementStatus = found
+	}
+
+	tx := database.DB.Begin()
+
+	var err error
+	if replacement == "" {
+		err = replaceDefinitionOnTasks(tx, userID, "status", status.Name, "")
+	} else {
+		err = moveTasksToStatus(tx, userID, status, replacementStatus)
+	}
+	if err != nil {
+		tx.Rollback()
+		respondReplaceError(c, err)
+		return
+	}
+
+	if err := renameViewFilter(tx, userID, "status", status.Name, replacement); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Delete(&status).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyTaskUpdate(userID)
+	c.JSON(http.StatusOK, gin.H{"message": "Status deleted successfully"})
+}
+
+func GetPriorities(c *gin.Context) {
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	priorities, err := userPriorities(space.OwnerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, priorities)
+}
+
+func CreatePriority(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	if !requireWorkspaceRole(c, models.WorkspaceOwner) {
+		return
+	}
+
+	var priorityReq PriorityRequest
+	if err := c.ShouldBindJSON(&priorityReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validatePriority(userID, priorityReq.Name); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A priority with this name already exists"})
+		return
+	} else if !errors.Is(err, errUnknownPriority) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	priority := models.TaskPriority{
+		UserID:   userID,
+		Name:     priorityReq.Name,
+		Color:    priorityReq.Color,
+		Position: priorityReq.Position,
+	}
+
+	if err := database.DB.Create(&priority).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyTaskUpdate(userID)
+	c.JSON(http.StatusCreated, priority)
+}
+
+// UpdatePriority edits a priority. Renaming it renames the priority on every
+// task, template and saved view that uses it.
+func UpdatePriority(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	if !requireWorkspaceRole(c, models.WorkspaceOwner) {
+		return
+	}
+
+	var priority models.TaskPriority
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&priority).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Priority not found"})
+		return
+	}
+
+	var priorityReq PriorityRequest
+	if err := c.ShouldBindJSON(&priorityReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	oldName := priority.Name
+	if priorityReq.Name != oldName {
+		if err := validatePriority(userID, priorityReq.Name); err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "A priority with this name already exists"})
+			return
+		}
+	}
+
+	priority.Name = priorityReq.Name
+	priority.Color = priorityReq.Color
+	priority.Position = priorityReq.Position
+
+	tx := database.DB.Begin()
+
+	if err := tx.Save(&priority).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if priority.Name != oldName {
+		if err := tx.Unscoped().Model(&models.Task{}).
+			Where("user_id = ? AND priority = ?", userID, oldName).
+			UpdateColumn("priority", priority.Name).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := renameTemplatePriority(tx, userID, oldName, priority.Name); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := renameViewFilter(tx, userID, "priority", oldName, priority.Name); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyTaskUpdate(userID)
+	c.JSON(http.StatusOK, priority)
+}
+
+// DeletePriority removes a priority. If tasks still use it, the
+// replace_with query parameter must name another priority to move them to.
+// Templates using it move to replace_with too, or to the default priority
+// when none is given, and saved views filtering on it follow replace_with
+// or stop filtering on priority.
+func DeletePriority(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	if !requireWorkspaceRole(c, models.WorkspaceOwner) {
+		return
+	}
+
+	var priority models.TaskPriority
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&priority).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Priority not found"})
+		return
+	}
+
+	var remaining int
+	if err := database.DB.Model(&models.TaskPriority{}).Where("user_id = ? AND id <> ?", userID, priority.ID).Count(&remaining).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if remaining == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You must keep at least one priority"})
+		return
+	}
+
+	replacement := c.Query("replace_with")
+	if replacement != "" {
+		if replacement == priority.Name {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "replace_with must name a different priority"})
+			return
+		}
+		if err := validatePriority(userID, replacement); err != nil {
+			respondDefinitionError(c, err)
+			return
+		}
+	}
+
+	tx := database.DB.Begin()
+
+	if err := replaceDefinitionOnTasks(tx, userID, "priority", priority.Name, replacement); err != nil {
+		tx.Rollback()
+		respondReplaceError(c, err)
+		return
+	}
+
+	if err := renameTemplatePriority(tx, userID, priority.Name, replacement); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := renameViewFilter(tx, userID, "priority", priority.Name, replacement); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Delete(&priority).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyTaskUpdate(userID)
+	c.JSON(http.StatusOK, gin.H{"message": "Priority deleted successfully"})
+}
+
+var errDefinitionInUse = errors.New("Tasks still use this value; pass replace_with to move them")
+
+// replaceDefinitionOnTasks moves every task (including deleted ones) whose
+// column equals name over to replacement. With no replacement it fails if
+// any task still uses name.
+func replaceDefinitionOnTasks(tx *gorm.DB, userID uint, column, name, replacement string) error {
+	tasks := tx.Unscoped().Model(&models.Task{}).Where("user_id = ? AND "+column+" = ?", userID, name)
+
+	if replacement == "" {
+		var inUse int
+		if err := tasks.Count(&inUse).Error; err != nil {
+			return err
+		}
+		if inUse > 0 {
+			return errDefinitionInUse
+		}
+		return nil
+	}
+
+	return tasks.UpdateColumn(column, replacement).Error
+}
+
+// moveTasksToStatus moves every task (including deleted ones) in status
+// over to replacement, each to the bottom of its column with the move in
+// its status history. Moving to another category isn't a transition the
+// tasks made, so the workflow rules don't apply, but their StartedAt and
+// CompletedAt follow the new category as in syncStatusTimestamps.
+func moveTasksToStatus(tx *gorm.DB, userID uint, status, replacement models.TaskStatus) error {
+	var tasks []models.Task
+	if err := tx.Unscoped().Where("user_id = ? AND status = ?", userID, status.Name).Order("rank asc, id asc").Find(&tasks).Error; err != nil {
+		return err
+	}
+
+	from := status
+	if from.Category != replacement.Category {
+		from = models.TaskStatus{}
+	}
+	now := time.Now()
+	for _, task := range tasks {
+		rank, err := nextRankInColumn(tx, spaceOf(task.UserID, task.WorkspaceID), replacement.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := workflow.Apply(&task, from, replacement, now); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&task).UpdateColumns(map[string]interface{}{
+			"status":       task.Status,
+			"rank":         rank,
+			"started_at":   task.StartedAt,
+			"completed_at": task.CompletedAt,
+		}).Error; err != nil {
+			return err
+		}
+		if err := recordStatusChange(tx, task.ID, userID, status.Name, replacement.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func respondReplaceError(c *gin.Context, err error) {
+	if errors.Is(err, errDefinitionInUse) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/database"
+	"taskmanager/dates"
+	"taskmanager/models"
+)
+
+// TaskResponse is a task as returned by the write endpoints, along with any
+// scheduling warnings the write produced.
+type TaskResponse struct {
+	models.Task
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type effortPeriod struct {
+	Start              string  `json:"start"`
+	EstimatedHours     float64 `json:"estimated_hours"`
+	OpenEstimatedHours float64 `json:"open_estimated_hours"`
+	StoryPoints        int     `json:"story_points"`
+	TasksDue           int     `json:"tasks_due"`
+	TrackedHours       float64 `json:"tracked_hours"`
+	CapacityHours      float64 `json:"capacity_hours"`
+	OverCapacity       bool    `json:"over_capacity"`
+}
+
+func findUser(userID uint) (models.User, error) {
+	var user models.User
+	err := database.DB.First(&user, userID).Error
+	return user, err
+}
+
+// withCapacityWarnings wraps task for a response to userID, warning when
+// the open work due on the task's due day is estimated above their daily
+// capacity. Capacity covers the user's personal tasks, so workspace tasks
+// and tasks shared with them get no warning. Failing to compute the warning
+// never fails the request.
+func withCapacityWarnings(userID uint, task models.Task) TaskResponse {
+	response := TaskResponse{Task: task}
+	if task.CompletedAt != nil || task.DueDate == nil || task.WorkspaceID != nil || task.UserID != userID {
+		return response
+	}
+
+	user, err := findUser(userID)
+	if err != nil {
+		log.Printf("Error loading capacity for user %d: %v", userID, err)
+		return response
+	}
+
+	day := dates.StartOfDay(*task.DueDate, dates.LoadLocation(user.Timezone))
+	var load float64
+	if err := spaceOf(userID, nil).scope(database.DB.Model(&models.Task{})).
+		Select("COALESCE(SUM(estimate_hours), 0)").
+		Where("completed_at IS NULL AND due_date >= ? AND due_date < ?", day, dates.AddDays(day, 1)).
+		Row().
+		Scan(&load); err != nil {
+		log.Printf("Error computing load for user %d on %s: %v", userID, day.Format("2006-01-02"), err)
+		return response
+	}
+
+	if load > user.DailyCapacityHours {
+		response.Warnings = append(response.Warnings, capacityWarning(day, load, user.DailyCapacityHours))
+	}
+	return response
+}
+
+// startOfPeriod returns the day itself for group=day and the Monday of its
+// week for group=week.
+func startOfPeriod(day time.Time, group string) time.Time {
+	if group == "week" {
+		return dates.AddDays(day, -((int(day.Weekday()) + 6) % 7))
+	}
+	return day
+}
+
+func capacityWarning(day time.Time, load, capacity float64) string {
+	return fmt.Sprintf("Open tasks due on %s are estimated at %.1f hours, over your daily capacity of %.1f hours",
+		day.Format("2006-01-02"), load, capacity)
+}
+
+// GetEffortSummary compares estimated and tracked effort per day or week
+// (group=day|week) between from and to, against the user's daily capacity.
+// Estimates count towards the day a task is due; tracked time towards the
+// day an entry started. Days are calendar days in the user's timezone.
+// Like capacity warnings, it covers the user's personal tasks only.
+func GetEffortSummary(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	group := c.DefaultQuery("group", "day")
+	if group != "day" && group != "week" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group must be day or week"})
+		return
+	}
+
+	user, err := findUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	loc := dates.LoadLocation(user.Timezone)
+
+	from, to, err := parseDateRange(c, 7, maxRangeDays(group), loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	personal := spaceOf(userID, nil)
+
+	var estimateRows []struct {
+		Day         time.Time
+		Hours       float64
+		OpenHours   float64
+		StoryPoints int
+		Tasks       int
+	}
+	if err := personal.scope(database.DB.Model(&models.Task{})).
+		Select("DATE(due_date AT TIME ZONE ?) AS day, "+
+			"COALESCE(SUM(estimate_hours), 0) AS hours, "+
+			"COALESCE(SUM(CASE WHEN completed_at IS NULL THEN estimate_hours ELSE 0 END), 0) AS open_hours, "+
+			"COALESCE(SUM(story_points), 0) AS story_points, "+
+			"COUNT(*) AS tasks", loc.String()).
+		Where("due_date >= ? AND due_date < ?", from, to).
+		Group("day").
+		Order("day asc").
+		Scan(&estimateRows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var trackedRows []struct {
+		Day     time.Time
+		Seconds int64
+	}
+	if err := database.DB.Model(&models.TimeEntry{}).
+		Select("DATE(started_at AT TIME ZONE ?) AS day, "+trackedSeconds+" AS seconds", loc.String()).
+		Where("user_id = ? AND started_at >= ? AND started_at < ?", userID, from, to).
+		Where("task_id IN (?)", personal.scope(database.DB.Unscoped().Table("tasks").Select("id")).QueryExpr()).
+		Group("day").
+		Scan(&trackedRows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	periodStart := func(day time.Time) time.Time {
+		return startOfPeriod(day, group)
+	}
+
+	var periods []*effortPeriod
+	byStart := make(map[string]*effortPeriod)
+	for day := from; day.Before(to); day = dates.AddDays(day, 1) {
+		key := periodStart(day).Format("2006-01-02")
+		period, ok := byStart[key]
+		if !ok {
+			period = &effortPeriod{Start: key}
+			byStart[key] = period
+			periods = append(periods, period)
+		}
+		period.CapacityHours += user.DailyCapacityHours
+	}
+
+	warnings := []string{}
+	for _, row := range estimateRows {
+		period := byStart[periodStart(row.Day).Format("2006-01-02")]
+		if period == nil {
+			continue
+		}
+		period.EstimatedHours += row.Hours
+		period.OpenEstimatedHours += row.OpenHours
+		period.StoryPoints += row.StoryPoints
+		period.TasksDue += row.Tasks
+
+		if row.OpenHours > user.DailyCapacityHours {
+			warnings = append(warnings, capacityWarning(row.Day, row.OpenHours, user.DailyCapacityHours))
+		}
+	}
+	for _, row := range trackedRows {
+		if period := byStart[periodStart(row.Day).Format("2006-01-02")]; period != nil {
+			period.TrackedHours += float64(row.Seconds) / 3600
+		}
+	}
+	for _, period := range periods {
+		period.OverCapacity = period.OpenEstimatedHours > period.CapacityHours
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":                 from.Format("2006-01-02"),
+		"to":                   dates.AddDays(to, -1).Format("2006-01-02"),
+		"group":                group,
+		"daily_capacity_hours": user.DailyCapacityHours,
+		"periods":              periods,
+		"warnings":             warnings,
+	})
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/database"
+	"taskmanager/dates"
+	"taskmanager/models"
+)
+
+// exportBatchSize is how many tasks are loaded at a time while streaming an
+// export, so large exports never sit in memory all at once.
+const exportBatchSize = 500
+
+// transferColumns are the CSV columns of an export, in order. Imports read
+// the same names by default.
+var transferColumns = []string{
+	"id", "title", "description", "status", "priority", "due_date",
+	"project", "tags", "estimate_hours", "story_points", "created_at", "completed_at",
+}
+
+// taskTransferRow is a task as it appears in an export. Due dates are
+// written the way the importer reads them: a plain date for all-day dates in
+// the user's timezone, RFC 3339 otherwise.
+type taskTransferRow struct {
+	ID            uint       `json:"id"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	Status        string     `json:"status"`
+	Priority      string     `json:"priority"`
+	DueDate       string     `json:"due_date,omitempty"`
+	Project       string     `json:"project,omitempty"`
+	Tags          []string   `json:"tags"`
+	EstimateHours *float64   `json:"estimate_hours"`
+	StoryPoints   *int       `json:"story_points"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CompletedAt   *time.Time `json:"completed_at"`
+}
+
+func newTaskTransferRow(task models.Task, projectNames map[uint]string, loc *time.Location) taskTransferRow {
+	row := taskTransferRow{
+		ID:            task.ID,
+		Title:         task.Title,
+		Description:   task.Description,
+		Status:        task.Status,
+		Priority:      task.Priority,
+		Tags:          []string{},
+		EstimateHours: task.EstimateHours,
+		StoryPoints:   task.StoryPoints,
+		CreatedAt:     task.CreatedAt,
+		CompletedAt:   task.CompletedAt,
+	}
+	if task.DueDate != nil {
+		if task.DueAllDay {
+			row.DueDate = task.DueDate.In(loc).Format(dates.DateLayout)
+		} else {
+			row.DueDate = task.DueDate.Format(time.RFC3339)
+		}
+	}
+	if task.ProjectID != nil {
+		row.Project = projectNames[*task.ProjectID]
+	}
+	for _, tag := range task.Tags {
+		row.Tags = append(row.Tags, tag.Name)
+	}
+	return row
+}
+
+// formulaPrefixes are the characters spreadsheet apps treat as the start of
+// a formula when they open a CSV.
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeCSVCell keeps user-written text from being run as a formula by
+// quoting it with a leading apostrophe. unescapeCSVCell undoes it on import.
+func escapeCSVCell(value string) string {
+	if value != "" && strings.ContainsRune(formulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func unescapeCSVCell(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+func (row taskTransferRow) csvRecord() []string {
+	record := []string{
+		strconv.FormatUint(uint64(row.ID), 10),
+		escapeCSVCell(row.Title),
+		escapeCSVCell(row.Description),
+		escapeCSVCell(row.Status),
+		escapeCSVCell(row.Priority),
+		row.DueDate,
+		escapeCSVCell(row.Project),
+		escapeCSVCell(strings.Join(row.Tags, tagSeparator)),
+		"",
+		"",
+		row.CreatedAt.Format(time.RFC3339),
+		"",
+	}
+	if row.EstimateHours != nil {
+		record[8] = strconv.FormatFloat(*row.EstimateHours, 'f', -1, 64)
+	}
+	if row.StoryPoints != nil {
+		record[9] = strconv.Itoa(*row.StoryPoints)
+	}
+	if row.CompletedAt != nil {
+		record[11] = row.CompletedAt.Format(time.RFC3339)
+	}
+	return record
+}
+
+// ExportTasks streams the tasks of the active space as CSV or JSON
+// (format=csv|json), applying the same filters and sort order as GetTasks.
+func ExportTasks(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+
+	query, err := filterTasks(c, space.scope(database.DB))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var projects []models.Project
+	if err := space.scope(database.DB).Find(&projects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	projectNames := make(map[uint]string, len(projects))
+	for _, project := range projects {
+		projectNames[project.ID] = project.Name
+	}
+
+	loc := userLocation(userID)
+	filename := fmt.Sprintf("tasks-%s.%s", time.Now().In(loc).Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	var csvWriter *csv.Writer
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		csvWriter = csv.NewWriter(c.Writer)
+		if err := csvWriter.Write(transferColumns); err != nil {
+			c.Error(err)
+			return
+		}
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.Writer.WriteString("[")
+	}
+	c.Status(http.StatusOK)
+
+	// Once streaming has started the status can't change, so a failure
+	// part-way through can only cut the export short.
+	written := 0
+	for offset := 0; ; offset += exportBatchSize {
+		var tasks []models.Task
+		if err := query.Preload("Tags").Offset(offset).Limit(exportBatchSize).Find(&tasks).Error; err != nil {
+			c.Error(err)
+			return
+		}
+
+		for _, task := range tasks {
+			row := newTaskTransferRow(task, projectNames, loc)
+			if csvWriter != nil {
+				if err := csvWriter.Write(row.csvRecord()); err != nil {
+					c.Error(err)
+					return
+				}
+				continue
+			}
+
+			encoded, err := json.Marshal(row)
+			if err != nil {
+				c.Error(err)
+				return
+			}
+			if written > 0 {
+				c.Writer.WriteString(",")
+			}
+			c.Writer.Write(encoded)
+			written++
+		}
+
+		if csvWriter != nil {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				c.Error(err)
+				return
+			}
+		}
+		c.Writer.Flush()
+
+		if len(tasks) < exportBatchSize {
+			break
+		}
+	}
+
+	if csvWriter == nil {
+		c.Writer.WriteString("]")
+	}
+}
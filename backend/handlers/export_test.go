@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+)
+
+func TestCSVRecordEscapesFormulas(t *testing.T) {
+	row := taskTransferRow{
+		ID:          7,
+		Title:       `=HYPERLINK("http://evil.example","click")`,
+		Description: "-1 day",
+		Status:      "+Review",
+		Priority:    "@high",
+		Project:     "Launch",
+		Tags:        []string{"ops"},
+		CreatedAt:   time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	record := row.csvRecord()
+	for i, want := range map[int]string{
+		1: `'=HYPERLINK("http://evil.example","click")`,
+		2: "'-1 day",
+		3: "'+Review",
+		4: "'@high",
+		6: "Launch",
+		7: "ops",
+	} {
+		if record[i] != want {
+			t.Errorf("%s = %q, want %q", transferColumns[i], record[i], want)
+		}
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(transferColumns)
+	writer.Write(record)
+	writer.Flush()
+
+	records, err := readCSVRecords(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := records[0]["title"]; got != row.Title {
+		t.Errorf("imported title = %q, want %q", got, row.Title)
+	}
+	if got := records[0]["description"]; got != row.Description {
+		t.Errorf("imported description = %q, want %q", got, row.Description)
+	}
+}
+
+func TestUnescapeCSVCellKeepsOtherApostrophes(t *testing.T) {
+	for _, value := range []string{"'quoted'", "'", "it's", ""} {
+		if got := unescapeCSVCell(value); got != value {
+			t.Errorf("unescapeCSVCell(%q) = %q", value, got)
+		}
+	}
+}
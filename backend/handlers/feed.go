@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/database"
+	"taskmanager/ical"
+	"taskmanager/middleware"
+	"taskmanager/models"
+)
+
+const calendarProdID = "-//Task Manager//Tasks//EN"
+
+// taskCalendar turns tasks into iCalendar components using the user's
+// definitions: status categories decide the iCal STATUS, and the order of
+// priorities is spread over iCal's 1 (highest) to 9 (lowest).
+type taskCalendar struct {
+	statuses     []models.TaskStatus
+	priorities   []models.TaskPriority
+	categories   map[string]string
+	levels       map[string]int
+	projectNames map[uint]string
+	uids         map[uint]string
+	loc          *time.Location
+}
+
+func newTaskCalendar(userID uint) (*taskCalendar, error) {
+	statuses, err := userStatuses(userID)
+	if err != nil {
+		return nil, err
+	}
+	priorities, err := userPriorities(userID)
+	if err != nil {
+		return nil, err
+	}
+	var projects []models.Project
+	if err := calendarSpace(userID).scope(database.DB).Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	var objects []models.CalendarObject
+	if err := database.DB.Where("user_id = ?", userID).Find(&objects).Error; err != nil {
+		return nil, err
+	}
+
+	cal := &taskCalendar{
+		statuses:     statuses,
+		priorities:   priorities,
+		categories:   make(map[string]string, len(statuses)),
+		levels:       make(map[string]int, len(priorities)),
+		projectNames: make(map[uint]string, len(projects)),
+		uids:         make(map[uint]string, len(objects)),
+		loc:          userLocation(userID),
+	}
+	for _, status := range statuses {
+		cal.categories[status.Name] = status.Category
+	}
+	// Priorities run from least to most urgent.
+	for i, priority := range priorities {
+		level := 5
+		if len(priorities) > 1 {
+			level = 9 - (8*i+(len(priorities)-1)/2)/(len(priorities)-1)
+		}
+		cal.levels[priority.Name] = level
+	}
+	for _, project := range projects {
+		cal.projectNames[project.ID] = project.Name
+	}
+	for _, object := range objects {
+		cal.uids[object.TaskID] = object.UID
+	}
+	return cal, nil
+}
+
+// taskUID identifies a task's VTODO: the UID a CalDAV client gave it, or
+// one derived from its ID. Its VEVENT gets a separate UID, as calendar
+// clients expect one component type per UID.
+func (cal *taskCalendar) taskUID(task models.Task) string {
+	if uid, ok := cal.uids[task.ID]; ok {
+		return uid
+	}
+	return fmt.Sprintf("task-%d@taskmanager", task.ID)
+}
+
+func taskEventUID(task models.Task) string {
+	return fmt.Sprintf("task-%d-due@taskmanager", task.ID)
+}
+
+// common adds the properties VTODO and VEVENT share.
+func (cal *taskCalendar) common(component *ical.Component, uid string, task models.Task) {
+	component.Add("UID", uid)
+	component.Add("DTSTAMP", ical.DateTime(task.UpdatedAt))
+	component.Add("CREATED", ical.DateTime(task.CreatedAt))
+	component.Add("LAST-MODIFIED", ical.DateTime(task.UpdatedAt))
+	component.Add("SUMMARY", ical.Text(task.Title))
+	if task.Description != "" {
+		component.Add("DESCRIPTION", ical.Text(task.Description))
+	}
+	if level, ok := cal.levels[task.Priority]; ok {
+		component.Add("PRIORITY", strconv.Itoa(level))
+	}
+
+	var categories []string
+	if task.ProjectID != nil && cal.projectNames[*task.ProjectID] != "" {
+		categories = append(categories, cal.projectNames[*task.ProjectID])
+	}
+	for _, tag := range task.Tags {
+		categories = append(categories, tag.Name)
+	}
+	if len(categories) > 0 {
+		component.Add("CATEGORIES", ical.TextList(categories))
+	}
+}
+
+// Todo renders a task as a VTODO, with or without a due date.
+func (cal *taskCalendar) Todo(task models.Task) ical.Component {
+	todo := ical.Component{Name: "VTODO"}
+	cal.common(&todo, cal.taskUID(task), task)
+
+	if task.DueDate != nil {
+		if task.DueAllDay {
+			todo.Add("DUE", ical.Date(task.DueDate.In(cal.loc)), "VALUE=DATE")
+		} else {
+			todo.Add("DUE", ical.DateTime(*task.DueDate))
+		}
+	}
+	if task.StartedAt != nil {
+		todo.Add("DTSTART", ical.DateTime(*task.StartedAt))
+	}
+
+	switch cal.categories[task.Status] {
+	case models.CategoryInProgress:
+		todo.Add("STATUS", "IN-PROCESS")
+	case models.CategoryDone:
+		todo.Add("STATUS", "COMPLETED")
+		todo.Add("PERCENT-COMPLETE", "100")
+		if task.CompletedAt != nil {
+			todo.Add("COMPLETED", ical.DateTime(*task.CompletedAt))
+		}
+	default:
+		todo.Add("STATUS", "NEEDS-ACTION")
+	}
+	return todo
+}
+
+// Event renders a dated task as a VEVENT on its due date, for calendar apps
+// that don't show to-dos: an all-day event for an all-day date, otherwise
+// a zero-length event at the due time. Finished tasks are marked as such in
+// the title since VEVENT has no completed state.
+func (cal *taskCalendar) Event(task models.Task) ical.Component {
+	event := ical.Component{Name: "VEVENT"}
+	cal.common(&event, taskEventUID(task), task)
+
+	if task.DueAllDay {
+		day := task.DueDate.In(cal.loc)
+		event.Add("DTSTART", ical.Date(day), "VALUE=DATE")
+		event.Add("DTEND", ical.Date(day.AddDate(0, 0, 1)), "VALUE=DATE")
+	} else {
+		event.Add("DTSTART", ical.DateTime(*task.DueDate))
+		event.Add("DTEND", ical.DateTime(*task.DueDate))
+	}
+	event.Add("TRANSP", "TRANSPARENT")
+
+	if cal.categories[task.Status] == models.CategoryDone {
+		for i, p := range event.Properties {
+			if p.Name == "SUMMARY" {
+				event.Properties[i].Value = ical.Text("✓ " + task.Title)
+			}
+		}
+	}
+	return event
+}
+
+func feedURL(c *gin.Context, token string) string {
+	return fmt.Sprintf("%s/feeds/%s/tasks.ics", baseURL(c), token)
+}
+
+// baseURL is the scheme and host the client reached the server on.
+func baseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// CreateFeedToken issues a new calendar feed token, replacing any previous
+// one. The token is only ever shown in this response.
+func CreateFeedToken(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	token, hash, err := middleware.GenerateFeedToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", userID).Update("feed_token_hash", hash).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token": token,
+		"url":   feedURL(c, token),
+	})
+}
+
+// RevokeFeedToken turns off the user's calendar feed; subscribed calendars
+// stop updating until a new token is issued.
+func RevokeFeedToken(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", userID).Update("feed_token_hash", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar feed token revoked"})
+}
+
+// GetTaskFeed serves the user's personal tasks as an iCalendar feed. Every
+// task is a VTODO and dated tasks are also a VEVENT; types=todo or
+// types=event limits the feed to one kind. Deleted tasks are left out.
+func GetTaskFeed(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	types := c.DefaultQuery("types", "all")
+	if types != "all" && types != "todo" && types != "event" {
+		c.String(http.StatusBadRequest, "types must be all, todo or event")
+		return
+	}
+
+	user, err := findUser(userID)
+	if err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	cal, err := newTaskCalendar(userID)
+	if err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	var tasks []models.Task
+	if err := calendarSpace(userID).scope(database.DB).Preload("Tags").Order("id asc").Find(&tasks).Error; err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	name := "Tasks"
+	if user.Name != "" {
+		name = "Tasks – " + user.Name
+	}
+	feed := ical.Calendar{
+		ProdID: calendarProdID,
+		Properties: []ical.Property{
+			{Name: "CALSCALE", Value: "GREGORIAN"},
+			{Name: "X-WR-CALNAME", Value: ical.Text(name)},
+			{Name: "X-WR-TIMEZONE", Value: cal.loc.String()},
+			{Name: "REFRESH-INTERVAL", Params: []string{"VALUE=DURATION"}, Value: "PT15M"},
+		},
+	}
+	for _, task := range tasks {
+		if types != "event" {
+			feed.Components = append(feed.Components, cal.Todo(task))
+		}
+		if types != "todo" && task.DueDate != nil {
+			feed.Components = append(feed.Components, cal.Event(task))
+		}
+	}
+
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", `inline; filename="tasks.ics"`)
+	c.Status(http.StatusOK)
+	feed.WriteTo(c.Writer)
+}
@@ -0,0 +1,457 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/database"
+	"taskmanager/dates"
+	"taskmanager/models"
+	"taskmanager/workflow"
+)
+
+const (
+	maxImportSize = 5 << 20
+	maxImportRows = 5000
+
+	// tagSeparator joins a task's tags in a single CSV cell.
+	tagSeparator = ";"
+)
+
+// importFields are the task fields an import can set.
+var importFields = []string{
+	"title", "description", "status", "priority", "due_date",
+	"project", "tags", "estimate_hours", "story_points",
+}
+
+type importRowResult struct {
+	Row    int      `json:"row"`
+	Errors []string `json:"errors"`
+}
+
+// importedTask is one validated row, ready to be created.
+type importedTask struct {
+	task   models.Task
+	status models.TaskStatus
+	tags   []string
+}
+
+// importLookups holds the user's definitions and projects, loaded once per
+// import so rows can be validated without a query each.
+type importLookups struct {
+	statuses        map[string]models.TaskStatus
+	priorities      map[string]bool
+	projects        map[string]models.Project
+	defaultStatus   models.TaskStatus
+	defaultPriority string
+	loc             *time.Location
+}
+
+// ImportTasks creates tasks from a CSV or JSON upload.
+//
+// The format comes from format=csv|json or, failing that, the Content-Type.
+// A CSV needs a header row; a JSON upload is an array of objects. Columns
+// are matched to task fields by name, and map[<field>]=<column> overrides
+// the column for a field, e.g. map[title]=Summary.
+//
+// Every row is validated first. With dry_run=true nothing is written and the
+// response lists each row's problems. Otherwise all valid rows are created
+// in a single transaction and invalid rows are skipped and reported.
+func ImportTasks(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	if !requireWorkspaceRole(c, models.WorkspaceMember) {
+		return
+	}
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	format := c.Query("format")
+	if format == "" {
+		switch c.ContentType() {
+		case "text/csv":
+			format = "csv"
+		case "application/json":
+			format = "json"
+		}
+	}
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+
+	mapping := c.QueryMap("map")
+	for field := range mapping {
+		if !isImportField(field) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown field %q in map, expected one of %s", field, strings.Join(importFields, ", "))})
+			return
+		}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Imports are limited to %d MB", maxImportSize>>20)})
+		return
+	}
+
+	var records []map[string]string
+	if format == "csv" {
+		records, err = readCSVRecords(body)
+	} else {
+		records, err = readJSONRecords(body)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(records) > maxImportRows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Imports are limited to %d rows", maxImportRows)})
+		return
+	}
+
+	lookups, err := loadImportLookups(space, userLocation(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var valid []importedTask
+	invalid := []importRowResult{}
+	for i, record := range records {
+		imported, problems := lookups.validateRow(mapRecord(record, mapping))
+		if len(problems) > 0 {
+			invalid = append(invalid, importRowResult{Row: i + 1, Errors: problems})
+			continue
+		}
+		valid = append(valid, imported)
+	}
+
+	response := gin.H{
+		"dry_run": dryRun,
+		"total":   len(records),
+		"valid":   len(valid),
+		"invalid": len(invalid),
+		"errors":  invalid,
+	}
+	if dryRun || len(valid) == 0 {
+		response["imported"] = 0
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	taskIDs, err := createImportedTasks(space, userID, valid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Import failed, no tasks were created: " + err.Error()})
+		return
+	}
+
+	space.notify()
+	response["imported"] = len(taskIDs)
+	response["task_ids"] = taskIDs
+	c.JSON(http.StatusOK, response)
+}
+
+func isImportField(field string) bool {
+	for _, f := range importFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// readCSVRecords turns a CSV with a header row into one map per data row,
+// keyed by lower-cased column name. Cells the exporter quoted against
+// formula injection are read back as written.
+func readCSVRecords(body []byte) ([]map[string]string, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("The CSV is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid CSV: %v", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	var records []map[string]string
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CSV: %v", err)
+		}
+
+		record := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(values) {
+				record[column] = unescapeCSVCell(values[i])
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// readJSONRecords turns an array of objects into one map per object, keyed
+// by lower-cased member name. Arrays (such as tags) are joined with
+// tagSeparator and other values are written out as text.
+func readJSONRecords(body []byte) ([]map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var objects []map[string]interface{}
+	if err := decoder.Decode(&objects); err != nil {
+		return nil, errors.New("Invalid JSON, expected an array of objects")
+	}
+
+	records := make([]map[string]string, 0, len(objects))
+	for _, object := range objects {
+		record := make(map[string]string, len(object))
+		for key, value := range object {
+			record[strings.ToLower(key)] = jsonValueText(value)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func jsonValueText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, jsonValueText(item))
+		}
+		return strings.Join(parts, tagSeparator)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// mapRecord picks each import field's value out of record, using the
+// column named in mapping or else the column with the field's own name.
+func mapRecord(record map[string]string, mapping map[string]string) map[string]string {
+	values := make(map[string]string, len(importFields))
+	for _, field := range importFields {
+		column := field
+		if mapped, ok := mapping[field]; ok {
+			column = strings.ToLower(strings.TrimSpace(mapped))
+		}
+		values[field] = strings.TrimSpace(record[column])
+	}
+	return values
+}
+
+// loadImportLookups loads the definitions and projects of space. Dates
+// are read in loc, the importing user's timezone.
+func loadImportLookups(space taskSpace, loc *time.Location) (*importLookups, error) {
+	statuses, err := userStatuses(space.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	priorities, err := userPriorities(space.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	var projects []models.Project
+	if err := space.scope(database.DB).Where("archived = ?", false).Find(&projects).Error; err != nil {
+		return nil, err
+	}
+
+	lookups := &importLookups{
+		statuses:   make(map[string]models.TaskStatus, len(statuses)),
+		priorities: make(map[string]bool, len(priorities)),
+		projects:   make(map[string]models.Project, len(projects)),
+		loc:        loc,
+	}
+	for _, status := range statuses {
+		lookups.statuses[status.Name] = status
+	}
+	for _, priority := range priorities {
+		lookups.priorities[priority.Name] = true
+	}
+	for _, project := range projects {
+		lookups.projects[strings.ToLower(project.Name)] = project
+	}
+	if len(statuses) > 0 {
+		lookups.defaultStatus = statuses[0]
+	}
+	if len(priorities) > 0 {
+		lookups.defaultPriority = priorities[0].Name
+	}
+	return lookups, nil
+}
+
+// validateRow builds a task from one row's field values, collecting every
+// problem rather than stopping at the first. Status and priority default to
+// the user's first status and priority when left blank.
+func (l *importLookups) validateRow(values map[string]string) (importedTask, []string) {
+	var problems []string
+	imported := importedTask{
+		task: models.Task{
+			Title:       values["title"],
+			Description: values["description"],
+			Priority:    l.defaultPriority,
+		},
+		status: l.defaultStatus,
+	}
+
+	if imported.task.Title == "" {
+		problems = append(problems, "title is required")
+	}
+
+	if name := values["status"]; name != "" {
+		status, ok := l.statuses[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown status %q", name))
+		}
+		imported.status = status
+	}
+
+	if name := values["priority"]; name != "" {
+		if !l.priorities[name] {
+			problems = append(problems, fmt.Sprintf("unknown priority %q", name))
+		}
+		imported.task.Priority = name
+	}
+
+	if value := values["due_date"]; value != "" {
+		dueDate, allDay, err := dates.Parse(value, l.loc)
+		if err != nil {
+			problems = append(problems, "due_date: "+err.Error())
+		} else {
+			imported.task.DueDate = &dueDate
+			imported.task.DueAllDay = allDay
+		}
+	}
+
+	if name := values["project"]; name != "" {
+		project, ok := l.projects[strings.ToLower(name)]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("project %q not found or archived", name))
+		} else {
+			imported.task.ProjectID = &project.ID
+		}
+	}
+
+	if value := values["tags"]; value != "" {
+		imported.tags = strings.Split(value, tagSeparator)
+		for _, tag := range imported.tags {
+			if len(normalizeTagName(tag)) > 50 {
+				problems = append(problems, fmt.Sprintf("tag %q is longer than 50 characters", tag))
+			}
+		}
+	}
+
+	if value := values["estimate_hours"]; value != "" {
+		hours, err := strconv.ParseFloat(value, 64)
+		if err != nil || hours < 0 || hours > 1000 {
+			problems = append(problems, "estimate_hours must be a number between 0 and 1000")
+		} else {
+			imported.task.EstimateHours = &hours
+		}
+	}
+
+	if value := values["story_points"]; value != "" {
+		points, err := strconv.Atoi(value)
+		if err != nil || points < 0 || points > 1000 {
+			problems = append(problems, "story_points must be an integer between 0 and 1000")
+		} else {
+			imported.task.StoryPoints = &points
+		}
+	}
+
+	return imported, problems
+}
+
+// createImportedTasks creates every validated task in space in one
+// transaction, so an import either lands completely or not at all. userID
+// is recorded as the tasks' creator in their history and as the author of
+// any mentions in their descriptions.
+func createImportedTasks(space taskSpace, userID uint, imported []importedTask) ([]uint, error) {
+	now := time.Now()
+	tx := database.DB.Begin()
+
+	taskIDs := make([]uint, 0, len(imported))
+	var notifications []models.Notification
+	for _, item := range imported {
+		task := item.task
+		space.place(&task)
+		if _, err := workflow.Apply(&task, models.TaskStatus{}, item.status, now); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		rank, err := nextRankInColumn(tx, space, task.Status)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		task.Rank = rank
+
+		if err := tx.Create(&task).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := recordStatusChange(tx, task.ID, userID, "", task.Status); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := recordRevision(tx, userID, models.TaskSnapshot{}, task, nil); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		if len(item.tags) > 0 {
+			tags, err := findOrCreateTags(tx, space.OwnerID, item.tags)
+			if err == nil && len(tags) > 0 {
+				err = tx.Model(&task).Association("Tags").Append(tags).Error
+			}
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+
+		mentioned, err := syncMentions(tx, task, nil, userID, task.Description)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		notifications = append(notifications, mentioned...)
+
+		taskIDs = append(taskIDs, task.ID)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	pushNotifications(notifications)
+	return taskIDs, nil
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/database"
+	"taskmanager/mentions"
+	"taskmanager/models"
+)
+
+// excerptLength is how many characters of the mentioning text a
+// notification keeps.
+const excerptLength = 200
+
+// mentionableUsers maps each handle of the task's collaborators to the
+// user it names. Email handles win over name handles, and a handle two
+// collaborators share is left out rather than guessed.
+func mentionableUsers(task models.Task) (map[string]uint, error) {
+	var users []models.User
+	if err := database.DB.Where("id IN (?)", taskCollaborators(task)).Order("id asc").Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	byHandle := make(map[string]uint)
+	ambiguous := make(map[string]bool)
+	add := func(handle string, userID uint) {
+		if id, ok := byHandle[handle]; ok && id != userID {
+			ambiguous[handle] = true
+			return
+		}
+		byHandle[handle] = userID
+	}
+
+	for _, user := range users {
+		if handle := mentions.EmailHandle(user.Email); handle != "" {
+			add(handle, user.ID)
+		}
+	}
+	emailHandles := make(map[string]bool, len(byHandle))
+	for handle := range byHandle {
+		emailHandles[handle] = true
+	}
+	for _, user := range users {
+		if handle := mentions.NameHandle(user.Name); handle != "" && !emailHandles[handle] {
+			add(handle, user.ID)
+		}
+	}
+
+	for handle := range ambiguous {
+		delete(byHandle, handle)
+	}
+	return byHandle, nil
+}
+
+// syncMentions brings the mention records of a task's description
+// (commentID nil) or of a comment in line with text, which authorID has
+// just written. Only the task's collaborators can be mentioned, and never
+// the author. Users mentioned for the first time get an inbox notification,
+// created in tx and returned so it can be pushed once tx commits.
+func syncMentions(tx *gorm.DB, task models.Task, commentID *uint, authorID uint, text string) ([]models.Notification, error) {
+	var mentioned []uint
+	if handles := mentions.Parse(text); len(handles) > 0 {
+		byHandle, err := mentionableUsers(task)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[uint]bool)
+		for _, handle := range handles {
+			if userID, ok := byHandle[handle]; ok && userID != authorID && !seen[userID] {
+				seen[userID] = true
+				mentioned = append(mentioned, userID)
+			}
+		}
+	}
+
+	scope := tx.Where("task_id = ?", task.ID)
+	if commentID != nil {
+		scope = scope.Where("comment_id = ?", *commentID)
+	} else {
+		scope = scope.Where("comment_id IS NULL")
+	}
+
+	var existing []models.Mention
+	if err := scope.Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	already := make(map[uint]bool, len(existing))
+	for _, mention := range existing {
+		already[mention.UserID] = true
+	}
+
+	// Users no longer mentioned lose the record, so mentioning them again
+	// later notifies them again.
+	if len(existing) > 0 {
+		stale := scope
+		if len(mentioned) > 0 {
+			stale = stale.Where("user_id NOT IN (?)", mentioned)
+		}
+		if err := stale.Delete(&models.Mention{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var notifications []models.Notification
+	var author *models.UserSummary
+	for _, userID := range mentioned {
+		if already[userID] {
+			continue
+		}
+		if author == nil {
+			author = &models.UserSummary{}
+			if err := tx.First(author, authorID).Error; err != nil {
+				return nil, err
+			}
+		}
+
+		mention := models.Mention{TaskID: task.ID, CommentID: commentID, UserID: userID, AuthorID: authorID}
+		if err := tx.Create(&mention).Error; err != nil {
+			return nil, err
+		}
+
+		notification := models.Notification{
+			UserID:    userID,
+			Type:      models.NotificationMention,
+			ActorID:   authorID,
+			TaskID:    task.ID,
+			CommentID: commentID,
+			Excerpt:   excerpt(text),
+		}
+		if err := tx.Create(&notification).Error; err != nil {
+			return nil, err
+		}
+		notification.Actor = *author
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
+
+// pushNotifications tells each notified user about their new inbox entry
+// over the WebSocket.
+func pushNotifications(notifications []models.Notification) {
+	for _, notification := range notifications {
+		notifyTaskEvent(notification.UserID, TaskEvent{Type: "notification.created", TaskID: notification.TaskID, Data: notification})
+	}
+}
+
+func excerpt(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= excerptLength {
+		return text
+	}
+	return string(runes[:excerptLength-1]) + "…"
+}
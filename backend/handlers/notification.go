@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/database"
+	"taskmanager/models"
+)
+
+// GetNotifications lists the user's inbox, newest first, with the number of
+// unread notifications. Pass ?unread=true to list only those.
+func GetNotifications(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	inbox := database.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+
+	var unread int
+	if err := inbox.Where("read_at IS NULL").Count(&unread).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := inbox
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+
+	pagination := parsePagination(c)
+	if err := query.Count(&pagination.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var notifications []models.Notification
+	if err := query.Preload("Actor").Order("created_at desc, id desc").Offset(pagination.Offset()).Limit(pagination.PageSize).Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"pagination":    pagination,
+		"unread":        unread,
+	})
+}
+
+// MarkNotificationRead marks one of the user's notifications as read.
+// Marking it again keeps the time it was first read.
+func MarkNotificationRead(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var notification models.Notification
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&notification).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := database.DB.Model(&notification).UpdateColumn("read_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		notification.ReadAt = &now
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+// MarkAllNotificationsRead empties the user's unread inbox.
+func MarkAllNotificationsRead(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	result := database.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		UpdateColumn("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": result.RowsAffected})
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/database"
+	"taskmanager/models"
+	"taskmanager/ranking"
+)
+
+// MoveTaskRequest places a task in a status column between two neighbours.
+// PrevID is the task that should end up directly above it and NextID the
+// one directly below; either may be omitted at the ends of the column.
+type MoveTaskRequest struct {
+	Status string `json:"status" binding:"required,max=50"`
+	PrevID *uint  `json:"prev_id"`
+	NextID *uint  `json:"next_id"`
+}
+
+var errNeighborNotFound = errors.New("Neighbor task not found in the target column")
+
+// nextRankInColumn returns a rank that places a task at the bottom of the
+// given status column of space's board.
+func nextRankInColumn(db *gorm.DB, space taskSpace, status string) (string, error) {
+	var ranks []string
+	if err := space.scope(db.Model(&models.Task{})).
+		Where("status = ?", status).
+		Order("rank desc").
+		Limit(1).
+		Pluck("rank", &ranks).Error; err != nil {
+		return "", err
+	}
+
+	if len(ranks) == 0 {
+		return ranking.After(""), nil
+	}
+	return ranking.After(ranks[0]), nil
+}
+
+func MoveTask(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleEditor)
+	if err != nil {
+		respondAccessError(c, err, "Task not found or you don't have permission")
+		return
+	}
+
+	var moveReq MoveTaskRequest
+	if err := c.ShouldBindJSON(&moveReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if (moveReq.PrevID != nil && *moveReq.PrevID == task.ID) || (moveReq.NextID != nil && *moveReq.NextID == task.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A task can't be its own neighbor"})
+		return
+	}
+
+	// Collaborators move the task within the board of the space it is in.
+	space := spaceOf(task.UserID, task.WorkspaceID)
+	before := task.Snapshot()
+	tx := database.DB.Begin()
+
+	rank, err := rankBetweenNeighbors(tx, space, task.ID, moveReq)
+	if errors.Is(err, ranking.ErrNoSpace) {
+		// The neighbours' ranks are too close together; renumber the
+		// column and try again with fresh values.
+		if err = rebalanceColumn(tx, space, moveReq.Status); err == nil {
+			rank, err = rankBetweenNeighbors(tx, space, task.ID, moveReq)
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, errNeighborNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if errors.Is(err, ranking.ErrNoSpace) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "prev_id must be directly above next_id"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if err := changeTaskStatus(tx, &task, moveReq.Status, userID); err != nil {
+		tx.Rollback()
+		respondStatusChangeError(c, err)
+		return
+	}
+
+	task.Rank = rank
+	if err := tx.Save(&task).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := recordRevision(tx, userID, before, task, nil); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyCollaborators(task)
+	c.JSON(http.StatusOK, task)
+}
+
+// rankBetweenNeighbors computes a rank for taskID inside the requested
+// column. When only one neighbour is given the other is looked up from the
+// database, so a client with a stale view can't put two tasks on one rank.
+func rankBetweenNeighbors(db *gorm.DB, space taskSpace, taskID uint, moveReq MoveTaskRequest) (string, error) {
+	column := space.scope(db.Model(&models.Task{})).Where("status = ? AND id <> ?", moveReq.Status, taskID)
+
+	var prevRank, nextRank string
+	if moveReq.PrevID != nil {
+		rank, err := neighborRank(column, *moveReq.PrevID)
+		if err != nil {
+			return "", err
+		}
+		prevRank = rank
+	}
+	if moveReq.NextID != nil {
+		rank, err := neighborRank(column, *moveReq.NextID)
+		if err != nil {
+			return "", err
+		}
+		nextRank = rank
+	}
+
+	var ranks []string
+	switch {
+	case moveReq.PrevID != nil && moveReq.NextID == nil:
+		if err := column.Where("rank > ?", prevRank).Order("rank asc").Limit(1).Pluck("rank", &ranks).Error; err != nil {
+			return "", err
+		}
+		if len(ranks) > 0 {
+			nextRank = ranks[0]
+		}
+	case moveReq.PrevID == nil && moveReq.NextID != nil:
+		if err := column.Where("rank < ?", nextRank).Order("rank desc").Limit(1).Pluck("rank", &ranks).Error; err != nil {
+			return "", err
+		}
+		if len(ranks) > 0 {
+			prevRank = ranks[0]
+		}
+	case moveReq.PrevID == nil && moveReq.NextID == nil:
+		if err := column.Order("rank desc").Limit(1).Pluck("rank", &ranks).Error; err != nil {
+			return "", err
+		}
+		if len(ranks) > 0 {
+			return ranking.After(ranks[0]), nil
+		}
+		return ranking.After(""), nil
+	}
+
+	return ranking.Between(prevRank, nextRank)
+}
+
+func neighborRank(column *gorm.DB, id uint) (string, error) {
+	var ranks []string
+	if err := column.Where("id = ?", id).Pluck("rank", &ranks).Error; err != nil {
+		return "", err
+	}
+	if len(ranks) == 0 {
+		return "", errNeighborNotFound
+	}
+	return ranks[0], nil
+}
+
+// rebalanceColumn spreads the ranks of every task in a status column of
+// space's board evenly over the key space, keeping their current order.
+func rebalanceColumn(db *gorm.DB, space taskSpace, status string) error {
+	var tasks []models.Task
+	if err := space.scope(db).Where("status = ?", status).Order("rank asc, id asc").Find(&tasks).Error; err != nil {
+		return err
+	}
+
+	ranks := ranking.Spread(len(tasks))
+	for i, task := range tasks {
+		if err := db.Model(&task).UpdateColumn("rank", ranks[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
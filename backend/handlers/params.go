@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/dates"
+)
+
+// Longest date ranges reports accept. Reports are built one day at a time,
+// so the span has to be bounded; grouping by week allows a longer one.
+const (
+	maxDailyRangeDays  = 366
+	maxWeeklyRangeDays = 5 * 366
+)
+
+// maxRangeDays returns the longest range a report grouped by group accepts.
+func maxRangeDays(group string) int {
+	if group == "week" {
+		return maxWeeklyRangeDays
+	}
+	return maxDailyRangeDays
+}
+
+// parseDateRange reads the inclusive from/to query parameters (YYYY-MM-DD)
+// and returns them as a half-open [from, to) range of days in loc. Missing
+// bounds default to the defaultDays days ending today, and ranges longer
+// than maxDays days are rejected.
+func parseDateRange(c *gin.Context, defaultDays, maxDays int, loc *time.Location) (time.Time, time.Time, error) {
+	today := dates.StartOfDay(time.Now(), loc)
+
+	to := today
+	if toStr := c.Query("to"); toStr != "" {
+		parsed, err := dates.ParseDay(toStr, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("Invalid to date, expected YYYY-MM-DD")
+		}
+		to = parsed
+	}
+
+	from := dates.AddDays(to, -(defaultDays - 1))
+	if fromStr := c.Query("from"); fromStr != "" {
+		parsed, err := dates.ParseDay(fromStr, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("Invalid from date, expected YYYY-MM-DD")
+		}
+		from = parsed
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("from must not be after to")
+	}
+	end := dates.AddDays(to, 1)
+	if dates.AddDays(from, maxDays).Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("The range must not span more than %d days", maxDays)
+	}
+	return from, end, nil
+}
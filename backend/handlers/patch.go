@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/database"
+	"taskmanager/dates"
+	"taskmanager/models"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// taskPatchFields are the members of the JSON document a task patch is
+// applied to. Anything else in the patched document is rejected.
+var taskPatchFields = map[string]bool{
+	"title":          true,
+	"description":    true,
+	"status":         true,
+	"priority":       true,
+	"due_date":       true,
+	"project_id":     true,
+	"estimate_hours": true,
+	"story_points":   true,
+}
+
+type patchFieldError struct {
+	field  string
+	reason string
+}
+
+func (e *patchFieldError) Error() string {
+	return fmt.Sprintf("%s %s", e.field, e.reason)
+}
+
+// taskPatchDocument renders the editable fields of task as the JSON document
+// patches are applied to. An all-day due date is written as a plain date in
+// loc, so a patch that doesn't touch it round-trips unchanged.
+func taskPatchDocument(task models.Task, loc *time.Location) ([]byte, error) {
+	var dueDate *string
+	if task.DueDate != nil {
+		formatted := task.DueDate.Format(time.RFC3339)
+		if task.DueAllDay {
+			formatted = task.DueDate.In(loc).Format(dates.DateLayout)
+		}
+		dueDate = &formatted
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"title":          task.Title,
+		"description":    task.Description,
+		"status":         task.Status,
+		"priority":       task.Priority,
+		"due_date":       dueDate,
+		"project_id":     task.ProjectID,
+		"estimate_hours": task.EstimateHours,
+		"story_points":   task.StoryPoints,
+	})
+}
+
+// applyTaskPatch applies body to doc according to contentType: a JSON Patch
+// (RFC 6902) for application/json-patch+json and a JSON Merge Patch
+// (RFC 7396) for application/merge-patch+json or plain application/json.
+func applyTaskPatch(contentType string, doc, body []byte) ([]byte, int, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil && contentType != "" {
+		return nil, http.StatusUnsupportedMediaType, errors.New("Invalid Content-Type")
+	}
+
+	switch mediaType {
+	case jsonPatchContentType:
+		patch, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		patched, err := patch.Apply(doc)
+		if err != nil {
+			return nil, http.StatusUnprocessableEntity, err
+		}
+		return patched, 0, nil
+	case mergePatchContentType, "application/json", "":
+		if !json.Valid(body) {
+			return nil, http.StatusBadRequest, errors.New("Invalid JSON")
+		}
+		patched, err := jsonpatch.MergePatch(doc, body)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		return patched, 0, nil
+	}
+	return nil, http.StatusUnsupportedMediaType, fmt.Errorf("Unsupported Content-Type, use %s or %s", mergePatchContentType, jsonPatchContentType)
+}
+
+// changedPatchFields compares the task document before and after the patch
+// and returns the raw new value of every member that changed. A member the
+// patch removed is reported as null.
+func changedPatchFields(before, after []byte) (map[string]json.RawMessage, error) {
+	var original, patched map[string]json.RawMessage
+	if err := json.Unmarshal(before, &original); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &patched); err != nil {
+		return nil, &patchFieldError{field: "patch", reason: "must leave the task as a JSON object"}
+	}
+
+	changed := make(map[string]json.RawMessage)
+	for field, value := range patched {
+		if !taskPatchFields[field] {
+			return nil, &patchFieldError{field: field, reason: "is not a task field"}
+		}
+		if !jsonEqual(original[field], value) {
+			changed[field] = value
+		}
+	}
+	for field := range original {
+		if _, ok := patched[field]; !ok && !jsonEqual(original[field], json.RawMessage("null")) {
+			changed[field] = json.RawMessage("null")
+		}
+	}
+	return changed, nil
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var bufA, bufB bytes.Buffer
+	if json.Compact(&bufA, a) != nil || json.Compact(&bufB, b) != nil {
+		return false
+	}
+	return bytes.Equal(bufA.Bytes(), bufB.Bytes())
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return jsonEqual(raw, json.RawMessage("null"))
+}
+
+// decodePatchString reads a required, non-empty string of at most maxLen
+// characters (0 for no limit).
+func decodePatchString(field string, raw json.RawMessage, maxLen int) (string, error) {
+	var value string
+	if isJSONNull(raw) || json.Unmarshal(raw, &value) != nil || value == "" {
+		return "", &patchFieldError{field: field, reason: "must be a non-empty string"}
+	}
+	if maxLen > 0 && len(value) > maxLen {
+		return "", &patchFieldError{field: field, reason: fmt.Sprintf("must be at most %d characters", maxLen)}
+	}
+	return value, nil
+}
+
+// PatchTask partially updates a task. Only the fields the patch touches are
+// validated and written; setting due_date, project_id, estimate_hours or
+// story_points to null clears them.
+func PatchTask(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleEditor)
+	if err != nil {
+		respondAccessError(c, err, "Task not found or you don't have permission")
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc := userLocation(userID)
+	doc, err := taskPatchDocument(task, loc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	patched, status, err := applyTaskPatch(c.ContentType(), doc, body)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	changed, err := changedPatchFields(doc, patched)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := task.Snapshot()
+	newStatus := task.Status
+
+	for field, raw := range changed {
+		switch field {
+		case "title":
+			task.Title, err = decodePatchString(field, raw, 0)
+		case "description":
+			task.Description = ""
+			if !isJSONNull(raw) && json.Unmarshal(raw, &task.Description) != nil {
+				err = &patchFieldError{field: field, reason: "must be a string"}
+			}
+		case "status":
+			newStatus, err = decodePatchString(field, raw, 50)
+		case "priority":
+			if task.Priority, err = decodePatchString(field, raw, 50); err == nil {
+				err = validatePriority(task.UserID, task.Priority)
+			}
+		case "due_date":
+			err = patchDueDate(&task, raw, loc)
+		case "project_id":
+			if !canChangeProject(userID, task) {
+				err = errOwnerOnly
+				break
+			}
+			err = patchProject(&task, raw)
+		case "estimate_hours":
+			task.EstimateHours = nil
+			if !isJSONNull(raw) {
+				var hours float64
+				if json.Unmarshal(raw, &hours) != nil || hours < 0 || hours > 1000 {
+					err = &patchFieldError{field: field, reason: "must be a number between 0 and 1000"}
+				}
+				task.EstimateHours = &hours
+			}
+		case "story_points":
+			task.StoryPoints = nil
+			if !isJSONNull(raw) {
+				var points int
+				if json.Unmarshal(raw, &points) != nil || points < 0 || points > 1000 {
+					err = &patchFieldError{field: field, reason: "must be an integer between 0 and 1000"}
+				}
+				task.StoryPoints = &points
+			}
+		}
+		if err != nil {
+			respondPatchError(c, err)
+			return
+		}
+	}
+
+	if newStatus != task.Status {
+		task.Rank, err = nextRankInColumn(database.DB, spaceOf(task.UserID, task.WorkspaceID), newStatus)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	tx := database.DB.Begin()
+
+	if err := changeTaskStatus(tx, &task, newStatus, userID); err != nil {
+		tx.Rollback()
+		respondStatusChangeError(c, err)
+		return
+	}
+
+	if err := tx.Save(&task).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := recordRevision(tx, userID, before, task, nil); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var notifications []models.Notification
+	if task.Description != before.Description {
+		if notifications, err = syncMentions(tx, task, nil, userID, task.Description); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pushNotifications(notifications)
+	notifyCollaborators(task)
+	c.JSON(http.StatusOK, withCapacityWarnings(userID, task))
+}
+
+func patchDueDate(task *models.Task, raw json.RawMessage, loc *time.Location) error {
+	if isJSONNull(raw) {
+		task.DueDate = nil
+		task.DueAllDay = false
+		return nil
+	}
+
+	var value string
+	if json.Unmarshal(raw, &value) != nil {
+		return &patchFieldError{field: "due_date", reason: "must be a string or null"}
+	}
+	dueDate, allDay, err := dates.Parse(value, loc)
+	if err != nil {
+		return err
+	}
+	task.DueDate = &dueDate
+	task.DueAllDay = allDay
+	return nil
+}
+
+func patchProject(task *models.Task, raw json.RawMessage) error {
+	if isJSONNull(raw) {
+		task.ProjectID = nil
+		return nil
+	}
+
+	var projectID uint
+	if json.Unmarshal(raw, &projectID) != nil {
+		return &patchFieldError{field: "project_id", reason: "must be a project ID or null"}
+	}
+	if _, err := findActiveProject(spaceOf(task.UserID, task.WorkspaceID), projectID); err != nil {
+		return &patchFieldError{field: "project_id", reason: "does not refer to an active project"}
+	}
+	task.ProjectID = &projectID
+	return nil
+}
+
+func respondPatchError(c *gin.Context, err error) {
+	var fieldErr *patchFieldError
+	if errors.As(err, &fieldErr) || errors.Is(err, dates.ErrInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, errOwnerOnly) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	respondDefinitionError(c, err)
+}
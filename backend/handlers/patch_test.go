@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"taskmanager/models"
+)
+
+func TestPatchDueDate(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone data unavailable:", err)
+	}
+	due := time.Date(2024, 3, 1, 5, 0, 0, 0, time.UTC)
+
+	task := models.Task{DueDate: &due, DueAllDay: true}
+	if err := patchDueDate(&task, json.RawMessage("null"), loc); err != nil {
+		t.Fatal(err)
+	}
+	if task.DueDate != nil || task.DueAllDay {
+		t.Errorf("clearing due_date left DueDate = %v, DueAllDay = %v", task.DueDate, task.DueAllDay)
+	}
+
+	if err := patchDueDate(&task, json.RawMessage(`"2024-03-10"`), loc); err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2024, 3, 10, 0, 0, 0, 0, loc)
+	if task.DueDate == nil || !task.DueDate.Equal(want) || !task.DueAllDay {
+		t.Errorf("all-day due date = %v (all day %v), want %v", task.DueDate, task.DueAllDay, want)
+	}
+
+	if err := patchDueDate(&task, json.RawMessage(`"2024-03-10T15:00:00Z"`), loc); err != nil {
+		t.Fatal(err)
+	}
+	if task.DueAllDay {
+		t.Error("an exact due time was marked all-day")
+	}
+
+	if err := patchDueDate(&task, json.RawMessage(`12`), loc); err == nil {
+		t.Error("expected a non-string due_date to be rejected")
+	}
+}
+
+func TestApplyTaskPatch(t *testing.T) {
+	doc := []byte(`{"title":"Write report","description":"draft","due_date":"2024-03-10"}`)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantChanged map[string]string
+	}{
+		{
+			name:        "merge patch clears due date",
+			contentType: mergePatchContentType,
+			body:        `{"due_date":null,"title":"Final report"}`,
+			wantChanged: map[string]string{"due_date": "null", "title": `"Final report"`},
+		},
+		{
+			name:        "plain JSON is a merge patch",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"description":"done"}`,
+			wantChanged: map[string]string{"description": `"done"`},
+		},
+		{
+			name:        "JSON patch removes due date",
+			contentType: jsonPatchContentType,
+			body:        `[{"op":"remove","path":"/due_date"}]`,
+			wantChanged: map[string]string{"due_date": "null"},
+		},
+		{
+			name:        "failed JSON patch test",
+			contentType: jsonPatchContentType,
+			body:        `[{"op":"test","path":"/title","value":"Other"}]`,
+			wantStatus:  http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "unsupported content type",
+			contentType: "text/plain",
+			body:        `{}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patched, status, err := applyTaskPatch(tt.contentType, doc, []byte(tt.body))
+			if tt.wantStatus != 0 {
+				if err == nil || status != tt.wantStatus {
+					t.Fatalf("status = %d, err = %v, want %d", status, err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			changed, err := changedPatchFields(doc, patched)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(changed) != len(tt.wantChanged) {
+				t.Errorf("changed = %s, want %v", changed, tt.wantChanged)
+			}
+			for field, want := range tt.wantChanged {
+				if !jsonEqual(changed[field], json.RawMessage(want)) {
+					t.Errorf("%s = %s, want %s", field, changed[field], want)
+				}
+			}
+		})
+	}
+}
+
+func TestChangedPatchFieldsRejectsUnknownFields(t *testing.T) {
+	doc := []byte(`{"title":"Write report"}`)
+	if _, err := changedPatchFields(doc, []byte(`{"title":"Write report","user_id":2}`)); err == nil {
+		t.Error("expected a patch adding user_id to be rejected")
+	}
+	if _, err := changedPatchFields(doc, []byte(`[]`)); err == nil {
+		t.Error("expected a patch replacing the document with an array to be rejected")
+	}
+}
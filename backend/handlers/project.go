@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/database"
+	"taskmanager/models"
+)
+
+type ProjectRequest struct {
+	Name     string `json:"name" binding:"required,max=100"`
+	Color    string `json:"color" binding:"omitempty,hexcolor"`
+	Archived bool   `json:"archived"`
+}
+
+type MoveTaskProjectRequest struct {
+	ProjectID *uint `json:"project_id"`
+}
+
+func findUserProject(userID uint, projectID interface{}) (models.Project, error) {
+	var project models.Project
+	err := database.DB.Where("id = ? AND user_id = ?", projectID, userID).First(&project).Error
+	return project, err
+}
+
+// findActiveProject loads a project of space that tasks can be put in,
+// refusing archived projects, which can't receive new tasks.
+func findActiveProject(space taskSpace, projectID uint) (models.Project, error) {
+	var project models.Project
+	err := space.scope(database.DB).Where("id = ? AND archived = ?", projectID, false).First(&project).Error
+	return project, err
+}
+
+func GetProjects(c *gin.Context) {
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := space.scope(database.DB)
+	if c.Query("archived") != "true" {
+		query = query.Where("archived = ?", false)
+	}
+
+	var projects []models.Project
+	if err := query.Order("name asc").Find(&projects).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, projects)
+}
+
+func CreateProject(c *gin.Context) {
+	if !requireWorkspaceRole(c, models.WorkspaceMember) {
+		return
+	}
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var projectReq ProjectRequest
+	if err := c.ShouldBindJSON(&projectReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project := models.Project{
+		UserID:      space.OwnerID,
+		Name:        projectReq.Name,
+		Color:       projectReq.Color,
+		Archived:    projectReq.Archived,
+		WorkspaceID: space.WorkspaceID,
+	}
+
+	if err := database.DB.Create(&project).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	space.notify()
+	c.JSON(http.StatusCreated, project)
+}
+
+func UpdateProject(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	project, err := findProjectFor(userID, c.Param("id"), roleOwner)
+	if err != nil {
+		respondAccessError(c, err, "Project not found")
+		return
+	}
+
+	var projectReq ProjectRequest
+	if err := c.ShouldBindJSON(&projectReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project.Name = projectReq.Name
+	project.Color = projectReq.Color
+	project.Archived = projectReq.Archived
+
+	if err := database.DB.Save(&project).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyProjectCollaborators(project)
+	c.JSON(http.StatusOK, project)
+}
+
+// DeleteProject removes a project. Its tasks are kept and moved back to the
+// user's unsorted list rather than being deleted with it, and the project's
+// shares are revoked.
+func DeleteProject(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	project, err := findProjectFor(userID, c.Param("id"), roleOwner)
+	if err != nil {
+		respondAccessError(c, err, "Project not found")
+		return
+	}
+
+	collaborators := projectCollaborators(project)
+
+	tx := database.DB.Begin()
+
+	if err := tx.Model(&models.Task{}).Where("project_id = ?", project.ID).Update("project_id", nil).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Where("project_id = ?", project.ID).Delete(&models.Share{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Delete(&project).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, id := range collaborators {
+		notifyTaskUpdate(id)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Project deleted successfully"})
+}
+
+func GetProjectTasks(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	project, err := findProjectFor(userID, c.Param("id"), models.RoleViewer)
+	if err != nil {
+		respondAccessError(c, err, "Project not found")
+		return
+	}
+
+	query, err := filterTasks(c, database.DB.Where("project_id = ?", project.ID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tasks []models.Task
+	if err := query.Preload("Tags").Preload("Assignees").Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// GetProjectTaskCounts returns the number of tasks in each status column of
+// a project's board, using the owner's statuses.
+func GetProjectTaskCounts(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	project, err := findProjectFor(userID, c.Param("id"), models.RoleViewer)
+	if err != nil {
+		respondAccessError(c, err, "Project not found")
+		return
+	}
+
+	var rows []struct {
+		Status string
+		Count  int
+	}
+	if err := database.DB.Model(&models.Task{}).
+		Select("status, count(*) as count").
+		Where("project_id = ?", project.ID).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	statuses, err := userStatuses(project.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	counts := make(map[string]int, len(statuses))
+	for _, status := range statuses {
+		counts[status.Name] = 0
+	}
+	total := 0
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+		total += row.Count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"project_id": project.ID,
+		"counts":     counts,
+		"total":      total,
+	})
+}
+
+// MoveTaskToProject reassigns a task to another project, or back to the
+// unsorted list when project_id is null.
+func MoveTaskToProject(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleEditor)
+	if err != nil {
+		respondAccessError(c, err, "Task not found or you don't have permission")
+		return
+	}
+	if !canChangeProject(userID, task) {
+		c.JSON(http.StatusForbidden, gin.H{"error": errOwnerOnly.Error()})
+		return
+	}
+
+	var moveReq MoveTaskProjectRequest
+	if err := c.ShouldBindJSON(&moveReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if moveReq.ProjectID != nil {
+		if _, err := findActiveProject(spaceOf(task.UserID, task.WorkspaceID), *moveReq.ProjectID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Project not found or archived"})
+			return
+		}
+	}
+
+	previous := taskCollaborators(task)
+	before := task.Snapshot()
+	task.ProjectID = moveReq.ProjectID
+
+	tx := database.DB.Begin()
+
+	if err := tx.Model(&task).Update("project_id", moveReq.ProjectID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := recordRevision(tx, userID, before, task, nil); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Users who could see the task through its old project lose it, so they
+	// are refreshed as well as those who see it now.
+	notified := make(map[uint]bool)
+	for _, id := range append(previous, taskCollaborators(task)...) {
+		if !notified[id] {
+			notified[id] = true
+			notifyTaskUpdate(id)
+		}
+	}
+	c.JSON(http.StatusOK, task)
+}
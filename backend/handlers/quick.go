@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/database"
+	"taskmanager/models"
+	"taskmanager/quickadd"
+)
+
+type QuickAddRequest struct {
+	Text      string `json:"text" binding:"required"`
+	Preview   bool   `json:"preview"`
+	Status    string `json:"status"`
+	ProjectID *uint  `json:"project_id"`
+}
+
+// QuickAddTask creates a task from a single line of text, reading the due
+// date, priority and tags out of it (see package quickadd). Relative dates
+// are resolved in the user's timezone. With preview set, in the body or as
+// ?preview=true, the line is only parsed and nothing is saved.
+func QuickAddTask(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	if !requireWorkspaceRole(c, models.WorkspaceMember) {
+		return
+	}
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req QuickAddRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	preview := req.Preview || c.Query("preview") == "true"
+
+	parsed := quickadd.Parse(req.Text, time.Now(), userLocation(userID))
+	if parsed.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required", "parsed": parsed})
+		return
+	}
+
+	// Priorities are matched without regard to case, so "!HIGH" works.
+	priorities, err := userPriorities(space.OwnerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if parsed.Priority != "" {
+		name := parsed.Priority
+		parsed.Priority = ""
+		for _, priority := range priorities {
+			if strings.EqualFold(priority.Name, name) {
+				parsed.Priority = priority.Name
+				break
+			}
+		}
+		if parsed.Priority == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown priority %q", name), "parsed": parsed})
+			return
+		}
+	} else if len(priorities) > 0 {
+		parsed.Priority = priorities[0].Name
+	}
+
+	for _, tag := range parsed.Tags {
+		if len(normalizeTagName(tag)) > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Tag %q is longer than 50 characters", tag), "parsed": parsed})
+			return
+		}
+	}
+
+	if req.ProjectID != nil {
+		if _, err := findActiveProject(space, *req.ProjectID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Project not found or archived"})
+			return
+		}
+	}
+
+	statusName := req.Status
+	if statusName == "" {
+		statuses, err := userStatuses(space.OwnerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		statusName = statuses[0].Name
+	}
+	status, err := findStatus(space.OwnerID, statusName)
+	if err != nil {
+		respondDefinitionError(c, err)
+		return
+	}
+
+	if preview {
+		c.JSON(http.StatusOK, gin.H{"preview": true, "parsed": parsed})
+		return
+	}
+
+	imported := importedTask{
+		task: models.Task{
+			Title:     parsed.Title,
+			Priority:  parsed.Priority,
+			DueDate:   parsed.DueDate,
+			DueAllDay: parsed.DueAllDay,
+			ProjectID: req.ProjectID,
+		},
+		status: status,
+		tags:   parsed.Tags,
+	}
+	taskIDs, err := createImportedTasks(space, userID, []importedTask{imported})
+	if err != nil {
+		respondStatusChangeError(c, err)
+		return
+	}
+
+	var task models.Task
+	if err := database.DB.Preload("Tags").First(&task, taskIDs[0]).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	space.notify()
+	c.JSON(http.StatusCreated, gin.H{"parsed": parsed, "task": withCapacityWarnings(userID, task)})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/database"
+	"taskmanager/models"
+)
+
+// recordRevision stores the change from before to the task's current state.
+// Nothing is stored when no tracked field changed, unless the write was a
+// revert, which is always recorded.
+func recordRevision(tx *gorm.DB, userID uint, before models.TaskSnapshot, task models.Task, revertedFrom *uint) error {
+	after := task.Snapshot()
+	changes := before.Diff(after)
+	if len(changes) == 0 && revertedFrom == nil {
+		return nil
+	}
+
+	revision := models.TaskRevision{
+		TaskID:       task.ID,
+		UserID:       userID,
+		Changes:      changes,
+		Snapshot:     after,
+		RevertedFrom: revertedFrom,
+	}
+	return tx.Create(&revision).Error
+}
+
+// GetTaskHistory lists a task's revisions, oldest first, each with the
+// fields it changed.
+func GetTaskHistory(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleViewer)
+	if err != nil {
+		respondAccessError(c, err, "Task not found")
+		return
+	}
+
+	query := database.DB.Model(&models.TaskRevision{}).Where("task_id = ?", task.ID)
+
+	pagination := parsePagination(c)
+	if err := query.Count(&pagination.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var revisions []models.TaskRevision
+	if err := query.Order("created_at asc, id asc").Offset(pagination.Offset()).Limit(pagination.PageSize).Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions":  revisions,
+		"pagination": pagination,
+	})
+}
+
+// RevertTask puts a task back into the state recorded by one of its
+// revisions. The revert is itself stored as a new revision, so it can be
+// undone the same way. The status change goes through the workflow like
+// any other, and a project that has since been archived or deleted blocks
+// the revert.
+func RevertTask(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleEditor)
+	if err != nil {
+		respondAccessError(c, err, "Task not found")
+		return
+	}
+
+	var revision models.TaskRevision
+	if err := database.DB.Where("id = ? AND task_id = ?", c.Param("revision"), task.ID).First(&revision).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+	target := revision.Snapshot
+
+	projectChanged := (target.ProjectID == nil) != (task.ProjectID == nil) ||
+		(target.ProjectID != nil && *target.ProjectID != *task.ProjectID)
+	if projectChanged && !canChangeProject(userID, task) {
+		c.JSON(http.StatusForbidden, gin.H{"error": errOwnerOnly.Error()})
+		return
+	}
+	if projectChanged && target.ProjectID != nil {
+		if _, err := findActiveProject(spaceOf(task.UserID, task.WorkspaceID), *target.ProjectID); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "The revision's project no longer exists or is archived"})
+			return
+		}
+	}
+
+	if target.Priority != task.Priority {
+		if err := validatePriority(task.UserID, target.Priority); err != nil {
+			respondDefinitionError(c, err)
+			return
+		}
+	}
+
+	before := task.Snapshot()
+
+	if target.Status != task.Status {
+		rank, err := nextRankInColumn(database.DB, spaceOf(task.UserID, task.WorkspaceID), target.Status)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		task.Rank = rank
+	}
+
+	task.Title = target.Title
+	task.Description = target.Description
+	task.Priority = target.Priority
+	task.DueDate = target.DueDate
+	task.DueAllDay = target.DueAllDay
+	task.ProjectID = target.ProjectID
+	task.EstimateHours = target.EstimateHours
+	task.StoryPoints = target.StoryPoints
+
+	tx := database.DB.Begin()
+
+	if err := changeTaskStatus(tx, &task, target.Status, userID); err != nil {
+		tx.Rollback()
+		respondStatusChangeError(c, err)
+		return
+	}
+
+	if err := tx.Save(&task).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := recordRevision(tx, userID, before, task, &revision.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var notifications []models.Notification
+	if task.Description != before.Description {
+		if notifications, err = syncMentions(tx, task, nil, userID, task.Description); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pushNotifications(notifications)
+	notifyCollaborators(task)
+	c.JSON(http.StatusOK, withCapacityWarnings(userID, task))
+}
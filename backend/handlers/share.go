@@ -0,0 +1,651 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/database"
+	"taskmanager/middleware"
+	"taskmanager/models"
+)
+
+// roleOwner is the implicit role of a task's or project's owner, above any
+// role a share can grant.
+const roleOwner = "owner"
+
+var roleLevels = map[string]int{
+	models.RoleViewer: 1,
+	models.RoleEditor: 2,
+	roleOwner:         3,
+}
+
+var (
+	errReadOnly          = errors.New("You only have view access to this item")
+	errOwnerOnly         = errors.New("Only the owner can move a task to another project")
+	errNotPermitted      = errors.New("You don't have permission to do this")
+	errInviteTokenNeeded = errors.New("This invitation can only be answered with the invite token it was sent with")
+)
+
+type ShareRequest struct {
+	Email string `json:"email" binding:"required,email,max=100"`
+	Role  string `json:"role" binding:"required,oneof=viewer editor"`
+}
+
+type ShareRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=viewer editor"`
+}
+
+// InvitationRequest answers an invitation. Token is the invite token, which
+// only invitations to addresses that weren't registered yet need.
+type InvitationRequest struct {
+	Token string `json:"token"`
+}
+
+// ShareResponse is a newly created share. InviteToken is set when the
+// invited address isn't registered yet; it is shown only this once, for
+// the owner to pass on to the invitee.
+type ShareResponse struct {
+	models.Share
+	InviteToken string `json:"invite_token,omitempty"`
+}
+
+// InvitationResponse is a pending share as shown to the invited user.
+type InvitationResponse struct {
+	models.Share
+	OwnerName  string `json:"owner_name"`
+	OwnerEmail string `json:"owner_email"`
+	// Title is the shared task's title or the shared project's name.
+	Title string `json:"title"`
+}
+
+// SharedTaskResponse is a task shared with the user, with the role they
+// hold on it.
+type SharedTaskResponse struct {
+	models.Task
+	Role string `json:"role"`
+}
+
+type SharedProjectResponse struct {
+	models.Project
+	Role string `json:"role"`
+}
+
+// bestRole returns the highest of roles, or "" if there are none.
+func bestRole(roles []string) string {
+	best := ""
+	for _, role := range roles {
+		if roleLevels[role] > roleLevels[best] {
+			best = role
+		}
+	}
+	return best
+}
+
+// acceptedShares limits a share query to shares userID has accepted.
+func acceptedShares(userID uint) *gorm.DB {
+	return database.DB.Model(&models.Share{}).Where("user_id = ? AND accepted_at IS NOT NULL", userID)
+}
+
+// taskRole returns the role userID holds on task: owner, the best role
+// granted by their workspace membership or by a share of the task or of
+// its project, or "" for none.
+func taskRole(userID uint, task models.Task) (string, error) {
+	if task.UserID == userID {
+		return roleOwner, nil
+	}
+
+	query := acceptedShares(userID)
+	if task.ProjectID != nil {
+		query = query.Where("task_id = ? OR project_id = ?", task.ID, *task.ProjectID)
+	} else {
+		query = query.Where("task_id = ?", task.ID)
+	}
+	return grantedRole(userID, task.WorkspaceID, query)
+}
+
+// visibleTasks narrows query to the tasks userID holds any role on, the
+// same ones taskRole returns a role for, so lists can check access in SQL
+// instead of once per task.
+func visibleTasks(query *gorm.DB, userID uint) *gorm.DB {
+	return query.Where(`tasks.user_id = ?
+		OR tasks.workspace_id IN (SELECT workspace_id FROM memberships WHERE user_id = ?)
+		OR EXISTS (SELECT 1 FROM shares WHERE shares.user_id = ? AND shares.accepted_at IS NOT NULL
+			AND (shares.task_id = tasks.id OR shares.project_id = tasks.project_id))`,
+		userID, userID, userID)
+}
+
+func projectRole(userID uint, project models.Project) (string, error) {
+	if project.UserID == userID {
+		return roleOwner, nil
+	}
+	return grantedRole(userID, project.WorkspaceID, acceptedShares(userID).Where("project_id = ?", project.ID))
+}
+
+// grantedRole combines the roles shares grant with the one the user's
+// membership of workspaceID, if any, grants.
+func grantedRole(userID uint, workspaceID *uint, shares *gorm.DB) (string, error) {
+	var roles []string
+	if err := shares.Pluck("role", &roles).Error; err != nil {
+		return "", err
+	}
+
+	if workspaceID != nil {
+		membership, err := workspaceRole(userID, *workspaceID)
+		if err != nil {
+			return "", err
+		}
+		if membership != "" {
+			roles = append(roles, workspaceTaskRoles[membership])
+		}
+	}
+	return bestRole(roles), nil
+}
+
+// findTaskFor loads a task userID holds at least role on, whether they own
+// it or it was shared with them. Tasks they can't see at all are reported
+// as not found; tasks they can only view fail with errReadOnly.
+func findTaskFor(userID uint, taskID string, role string) (models.Task, error) {
+	var task models.Task
+	if err := database.DB.Where("id = ?", taskID).First(&task).Error; err != nil {
+		return task, err
+	}
+
+	held, err := taskRole(userID, task)
+	if err != nil {
+		return task, err
+	}
+	if held == "" {
+		return task, gorm.ErrRecordNotFound
+	}
+	if roleLevels[held] < roleLevels[role] {
+		return task, insufficientRole(held)
+	}
+	return task, nil
+}
+
+// findProjectFor is findTaskFor for projects.
+func findProjectFor(userID uint, projectID string, role string) (models.Project, error) {
+	var project models.Project
+	if err := database.DB.Where("id = ?", projectID).First(&project).Error; err != nil {
+		return project, err
+	}
+
+	held, err := projectRole(userID, project)
+	if err != nil {
+		return project, err
+	}
+	if held == "" {
+		return project, gorm.ErrRecordNotFound
+	}
+	if roleLevels[held] < roleLevels[role] {
+		return project, insufficientRole(held)
+	}
+	return project, nil
+}
+
+// insufficientRole is the error for a user holding held where a higher
+// role is needed.
+func insufficientRole(held string) error {
+	if held == models.RoleViewer {
+		return errReadOnly
+	}
+	return errNotPermitted
+}
+
+func respondAccessError(c *gin.Context, err error, notFound string) {
+	switch {
+	case errors.Is(err, errReadOnly), errors.Is(err, errNotPermitted):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case gorm.IsRecordNotFoundError(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// taskCollaborators returns the owner of a task, every user a share of the
+// task or its project has been accepted by and, for a workspace task, the
+// workspace's members.
+func taskCollaborators(task models.Task) []uint {
+	query := database.DB.Model(&models.Share{}).Where("accepted_at IS NOT NULL")
+	if task.ProjectID != nil {
+		query = query.Where("task_id = ? OR project_id = ?", task.ID, *task.ProjectID)
+	} else {
+		query = query.Where("task_id = ?", task.ID)
+	}
+	return collaborators(task.UserID, task.WorkspaceID, query)
+}
+
+func projectCollaborators(project models.Project) []uint {
+	query := database.DB.Model(&models.Share{}).Where("accepted_at IS NOT NULL AND project_id = ?", project.ID)
+	return collaborators(project.UserID, project.WorkspaceID, query)
+}
+
+func collaborators(ownerID uint, workspaceID *uint, shares *gorm.DB) []uint {
+	userIDs := []uint{ownerID}
+	var others []uint
+	if err := shares.Pluck("DISTINCT user_id", &others).Error; err != nil {
+		// The owner is still notified; collaborators catch up on their next
+		// refresh.
+		return userIDs
+	}
+	if workspaceID != nil {
+		others = append(others, workspaceMembers(*workspaceID)...)
+	}
+
+	seen := map[uint]bool{ownerID: true}
+	for _, id := range others {
+		if !seen[id] {
+			seen[id] = true
+			userIDs = append(userIDs, id)
+		}
+	}
+	return userIDs
+}
+
+// notifyCollaborators sends the "update" refresh signal to everyone who
+// can see task.
+func notifyCollaborators(task models.Task) {
+	for _, userID := range taskCollaborators(task) {
+		notifyTaskUpdate(userID)
+	}
+}
+
+func notifyCollaboratorsEvent(task models.Task, event TaskEvent) {
+	for _, userID := range taskCollaborators(task) {
+		notifyTaskEvent(userID, event)
+	}
+}
+
+func notifyProjectCollaborators(project models.Project) {
+	for _, userID := range projectCollaborators(project) {
+		notifyTaskUpdate(userID)
+	}
+}
+
+// createShare invites email to share with the given role. The owner can't
+// invite themselves, and each address is invited to a task or project
+// once; PUT /shares/:id changes the role afterwards. An invitation to a
+// registered user is bound to them; any other gets an invite token.
+func createShare(c *gin.Context, share models.Share) {
+	userID := c.GetUint("userID")
+
+	var shareReq ShareRequest
+	if err := c.ShouldBindJSON(&shareReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	share.OwnerID = userID
+	share.Email = strings.ToLower(strings.TrimSpace(shareReq.Email))
+	share.Role = shareReq.Role
+
+	owner, err := findUser(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.EqualFold(owner.Email, share.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You can't share with yourself"})
+		return
+	}
+
+	existing := database.DB.Model(&models.Share{}).Where("email = ?", share.Email)
+	if share.TaskID != nil {
+		existing = existing.Where("task_id = ?", *share.TaskID)
+	} else {
+		existing = existing.Where("project_id = ?", *share.ProjectID)
+	}
+	var count int
+	if err := existing.Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Already shared with this email"})
+		return
+	}
+
+	response := ShareResponse{}
+	var invitee models.User
+	err = database.DB.Where("LOWER(email) = ?", share.Email).First(&invitee).Error
+	switch {
+	case err == nil:
+		share.UserID = &invitee.ID
+	case gorm.IsRecordNotFoundError(err):
+		// Whoever registers the address later hasn't shown they own it,
+		// so accepting takes the token the owner sends them.
+		token, hash, err := middleware.GenerateInviteToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		share.InviteTokenHash = &hash
+		response.InviteToken = token
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Create(&share).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if share.UserID != nil {
+		notifyTaskEvent(*share.UserID, TaskEvent{Type: "share.invited", TaskID: derefUint(share.TaskID), Data: share})
+	}
+
+	response.Share = share
+	c.JSON(http.StatusCreated, response)
+}
+
+func derefUint(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+func listShares(c *gin.Context, query *gorm.DB) {
+	var shares []models.Share
+	if err := query.Order("created_at asc").Find(&shares).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, shares)
+}
+
+func GetTaskShares(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findUserTask(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	listShares(c, database.DB.Where("task_id = ?", task.ID))
+}
+
+func CreateTaskShare(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findUserTask(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	createShare(c, models.Share{TaskID: &task.ID})
+}
+
+func GetProjectShares(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	project, err := findProjectFor(userID, c.Param("id"), roleOwner)
+	if err != nil {
+		respondAccessError(c, err, "Project not found")
+		return
+	}
+
+	listShares(c, database.DB.Where("project_id = ?", project.ID))
+}
+
+func CreateProjectShare(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	project, err := findProjectFor(userID, c.Param("id"), roleOwner)
+	if err != nil {
+		respondAccessError(c, err, "Project not found")
+		return
+	}
+
+	createShare(c, models.Share{ProjectID: &project.ID})
+}
+
+// UpdateShare changes the role a share grants. Only the owner can.
+func UpdateShare(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var share models.Share
+	if err := database.DB.Where("id = ? AND owner_id = ?", c.Param("id"), userID).First(&share).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share not found"})
+		return
+	}
+
+	var roleReq ShareRoleRequest
+	if err := c.ShouldBindJSON(&roleReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	share.Role = roleReq.Role
+	if err := database.DB.Save(&share).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if share.UserID != nil {
+		notifyTaskUpdate(*share.UserID)
+	}
+	c.JSON(http.StatusOK, share)
+}
+
+// DeleteShare ends a share. The owner can revoke it, and the user it was
+// shared with can leave it.
+func DeleteShare(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var share models.Share
+	if err := database.DB.Where("id = ? AND (owner_id = ? OR user_id = ?)", c.Param("id"), userID, userID).First(&share).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share not found"})
+		return
+	}
+
+	if err := database.DB.Delete(&share).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyTaskUpdate(share.OwnerID)
+	if share.UserID != nil {
+		notifyTaskUpdate(*share.UserID)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Share removed"})
+}
+
+// findInvitation loads a pending share the user may answer: one bound to
+// them, or one to their email address sent before they registered, if
+// token is its invite token.
+func findInvitation(userID uint, shareID, token string) (models.Share, error) {
+	var share models.Share
+
+	user, err := findUser(userID)
+	if err != nil {
+		return share, err
+	}
+
+	err = database.DB.Where("id = ? AND accepted_at IS NULL AND (user_id = ? OR (user_id IS NULL AND email = ?))", shareID, userID, strings.ToLower(user.Email)).
+		First(&share).Error
+	if err != nil {
+		return share, err
+	}
+	if share.UserID == nil {
+		if share.InviteTokenHash == nil || subtle.ConstantTimeCompare([]byte(middleware.HashInviteToken(token)), []byte(*share.InviteTokenHash)) != 1 {
+			return share, errInviteTokenNeeded
+		}
+	}
+	return share, nil
+}
+
+// answerInvitation reads the invitation an accept or decline request is
+// about, responding with an error if there is none the user may answer.
+func answerInvitation(c *gin.Context, userID uint) (models.Share, bool) {
+	var req InvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return models.Share{}, false
+	}
+
+	share, err := findInvitation(userID, c.Param("id"), req.Token)
+	switch {
+	case errors.Is(err, errInviteTokenNeeded):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return share, false
+	case gorm.IsRecordNotFoundError(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return share, false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return share, false
+	}
+	return share, true
+}
+
+// GetInvitations lists the shares waiting for the user to accept them.
+// Invitations sent before they registered aren't listed, as only the
+// invite token shows they were meant for this account.
+func GetInvitations(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var shares []models.Share
+	if err := database.DB.Where("user_id = ? AND accepted_at IS NULL", userID).
+		Preload("Owner").
+		Order("created_at desc").
+		Find(&shares).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitations := make([]InvitationResponse, 0, len(shares))
+	for _, share := range shares {
+		invitation := InvitationResponse{Share: share, OwnerName: share.Owner.Name, OwnerEmail: share.Owner.Email}
+		if share.TaskID != nil {
+			var task models.Task
+			if err := database.DB.Select("title").Where("id = ?", *share.TaskID).First(&task).Error; err == nil {
+				invitation.Title = task.Title
+			}
+		} else if share.ProjectID != nil {
+			var project models.Project
+			if err := database.DB.Select("name").Where("id = ?", *share.ProjectID).First(&project).Error; err == nil {
+				invitation.Title = project.Name
+			}
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+func AcceptInvitation(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	share, ok := answerInvitation(c, userID)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	share.UserID = &userID
+	share.AcceptedAt = &now
+	share.InviteTokenHash = nil
+	if err := database.DB.Save(&share).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyTaskEvent(share.OwnerID, TaskEvent{Type: "share.accepted", TaskID: derefUint(share.TaskID), Data: share})
+	notifyTaskUpdate(userID)
+	c.JSON(http.StatusOK, share)
+}
+
+func DeclineInvitation(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	share, ok := answerInvitation(c, userID)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(&share).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyTaskEvent(share.OwnerID, TaskEvent{Type: "share.declined", TaskID: derefUint(share.TaskID), Data: gin.H{"id": share.ID}})
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+}
+
+// GetSharedWithMe lists the projects and tasks other users have shared with
+// the user, each with the role they hold. Tasks include those in shared
+// projects.
+func GetSharedWithMe(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var shares []models.Share
+	if err := acceptedShares(userID).Find(&shares).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	taskRoles := make(map[uint][]string)
+	projectRoles := make(map[uint][]string)
+	taskIDs := []uint{}
+	projectIDs := []uint{}
+	for _, share := range shares {
+		if share.TaskID != nil {
+			taskRoles[*share.TaskID] = append(taskRoles[*share.TaskID], share.Role)
+			taskIDs = append(taskIDs, *share.TaskID)
+		}
+		if share.ProjectID != nil {
+			projectRoles[*share.ProjectID] = append(projectRoles[*share.ProjectID], share.Role)
+			projectIDs = append(projectIDs, *share.ProjectID)
+		}
+	}
+
+	var projects []models.Project
+	if len(projectIDs) > 0 {
+		if err := database.DB.Where("id IN (?)", projectIDs).Order("name asc").Find(&projects).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var tasks []models.Task
+	if len(shares) > 0 {
+		if err := database.DB.Where("id IN (?) OR project_id IN (?)", append(taskIDs, 0), append(projectIDs, 0)).
+			Preload("Tags").
+			Preload("Assignees").
+			Order("id asc").
+			Find(&tasks).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	sharedProjects := make([]SharedProjectResponse, 0, len(projects))
+	for _, project := range projects {
+		sharedProjects = append(sharedProjects, SharedProjectResponse{Project: project, Role: bestRole(projectRoles[project.ID])})
+	}
+
+	sharedTasks := make([]SharedTaskResponse, 0, len(tasks))
+	for _, task := range tasks {
+		roles := append([]string{}, taskRoles[task.ID]...)
+		if task.ProjectID != nil {
+			roles = append(roles, projectRoles[*task.ProjectID]...)
+		}
+		sharedTasks = append(sharedTasks, SharedTaskResponse{Task: task, Role: bestRole(roles)})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"projects": sharedProjects,
+		"tasks":    sharedTasks,
+	})
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/database"
+	"taskmanager/dates"
+	"taskmanager/models"
+)
+
+type completionPeriod struct {
+	Start     string `json:"start"`
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+}
+
+type burndownPoint struct {
+	Day  string `json:"day"`
+	Open int    `json:"open"`
+}
+
+type definitionCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// statsScope limits the statistics to the tasks of space, and to one
+// project when project_id is given ("none" for tasks without a project).
+func statsScope(c *gin.Context, space taskSpace) (func() *gorm.DB, error) {
+	projectIDStr := c.Query("project_id")
+	var projectID uint64
+	if projectIDStr != "" && projectIDStr != "none" {
+		var err error
+		if projectID, err = strconv.ParseUint(projectIDStr, 10, 32); err != nil {
+			return nil, errors.New("Invalid project_id")
+		}
+	}
+
+	return func() *gorm.DB {
+		query := space.scope(database.DB.Model(&models.Task{}))
+		switch {
+		case projectIDStr == "none":
+			query = query.Where("project_id IS NULL")
+		case projectIDStr != "":
+			query = query.Where("project_id = ?", projectID)
+		}
+		return query
+	}, nil
+}
+
+// GetStats reports on the tasks of the active space: current counts by status and
+// priority, how many open tasks are overdue, tasks created and completed
+// per day or week (group=day|week) between from and to, the average cycle
+// time from creation to completion of the tasks completed in that range,
+// and a burndown of open tasks at the end of each day. Everything is
+// aggregated in the database; days are calendar days in the user's
+// timezone. Deleted tasks are left out.
+func GetStats(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	group := c.DefaultQuery("group", "day")
+	if group != "day" && group != "week" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group must be day or week"})
+		return
+	}
+
+	loc := userLocation(userID)
+	from, to, err := parseDateRange(c, 30, maxRangeDays(group), loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tasks, err := statsScope(c, space)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	statuses, err := userStatuses(space.OwnerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	priorities, err := userPriorities(space.OwnerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	statusNames := make([]string, len(statuses))
+	for i, status := range statuses {
+		statusNames[i] = status.Name
+	}
+	priorityNames := make([]string, len(priorities))
+	for i, priority := range priorities {
+		priorityNames[i] = priority.Name
+	}
+
+	byStatus, err := countByDefinition(tasks(), "status", statusNames)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	byPriority, err := countByDefinition(tasks(), "priority", priorityNames)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// An all-day task is overdue once its day is over, a timed one once its
+	// due time has passed.
+	now := time.Now()
+	var totals struct {
+		Total   int
+		Open    int
+		Overdue int
+	}
+	if err := tasks().
+		Select("COUNT(*) AS total, "+
+			"COUNT(*) FILTER (WHERE completed_at IS NULL) AS open, "+
+			"COUNT(*) FILTER (WHERE completed_at IS NULL AND ((due_all_day AND due_date < ?) OR (NOT due_all_day AND due_date < ?))) AS overdue",
+			dates.StartOfDay(now, loc), now).
+		Scan(&totals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var cycle struct {
+		Completed    int
+		AverageHours *float64
+	}
+	if err := tasks().
+		Select("COUNT(*) AS completed, AVG(EXTRACT(EPOCH FROM completed_at - created_at)) / 3600 AS average_hours").
+		Where("completed_at >= ? AND completed_at < ?", from, to).
+		Scan(&cycle).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := countPerDay(tasks(), "created_at", from, to, loc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	completed, err := countPerDay(tasks(), "completed_at", from, to, loc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The burndown starts from the tasks still open when the range began
+	// and follows the daily counts from there.
+	var openAtStart int
+	if err := tasks().
+		Where("created_at < ? AND (completed_at IS NULL OR completed_at >= ?)", from, from).
+		Count(&openAtStart).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	periods := []*completionPeriod{}
+	byStart := make(map[string]*completionPeriod)
+	burndown := []burndownPoint{}
+	open := openAtStart
+	for day := from; day.Before(to); day = dates.AddDays(day, 1) {
+		key := day.Format("2006-01-02")
+
+		start := startOfPeriod(day, group).Format("2006-01-02")
+		period, ok := byStart[start]
+		if !ok {
+			period = &completionPeriod{Start: start}
+			byStart[start] = period
+			periods = append(periods, period)
+		}
+		period.Created += created[key]
+		period.Completed += completed[key]
+
+		open += created[key] - completed[key]
+		burndown = append(burndown, burndownPoint{Day: key, Open: open})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":                from.Format("2006-01-02"),
+		"to":                  dates.AddDays(to, -1).Format("2006-01-02"),
+		"group":               group,
+		"total":               totals.Total,
+		"open":                totals.Open,
+		"overdue":             totals.Overdue,
+		"by_status":           byStatus,
+		"by_priority":         byPriority,
+		"completed_in_range":  cycle.Completed,
+		"average_cycle_hours": cycle.AverageHours,
+		"periods":             periods,
+		"burndown":            burndown,
+	})
+}
+
+// countPerDay counts tasks by the calendar day, in loc, of a timestamp
+// column, keyed by YYYY-MM-DD.
+func countPerDay(query *gorm.DB, column string, from, to time.Time, loc *time.Location) (map[string]int, error) {
+	var rows []struct {
+		Day   time.Time
+		Count int
+	}
+	if err := query.
+		Select("DATE("+column+" AT TIME ZONE ?) AS day, COUNT(*) AS count", loc.String()).
+		Where(column+" >= ? AND "+column+" < ?", from, to).
+		Group("day").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Day.Format("2006-01-02")] = row.Count
+	}
+	return counts, nil
+}
+
+// countByDefinition counts tasks per value of column, listing names (the
+// user's statuses or priorities, in their order) first, zeros included, and
+// then any values tasks hold that are no longer defined.
+func countByDefinition(query *gorm.DB, column string, names []string) ([]definitionCount, error) {
+	var rows []struct {
+		Name  string
+		Count int
+	}
+	if err := query.
+		Select(column + " AS name, COUNT(*) AS count").
+		Group(column).
+		Order(column + " asc").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Name] = row.Count
+	}
+
+	result := make([]definitionCount, 0, len(names))
+	listed := make(map[string]bool, len(names))
+	for _, name := range names {
+		result = append(result, definitionCount{Name: name, Count: counts[name]})
+		listed[name] = true
+	}
+	for _, row := range rows {
+		if !listed[row.Name] {
+			result = append(result, definitionCount{Name: row.Name, Count: row.Count})
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/database"
+	"taskmanager/models"
+	"taskmanager/workflow"
+)
+
+// changeTaskStatus moves an existing task to status through the workflow and
+// records the change in its status history. The caller saves the task.
+// Statuses are the task owner's; userID is whoever made the change.
+func changeTaskStatus(tx *gorm.DB, task *models.Task, status string, userID uint) error {
+	if task.Status == status {
+		return nil
+	}
+
+	to, err := findStatus(task.UserID, status)
+	if err != nil {
+		return err
+	}
+
+	// A task whose current status no longer exists is treated like a new
+	// one, so it can always be moved somewhere valid.
+	from, err := findStatus(task.UserID, task.Status)
+	if errors.Is(err, errUnknownStatus) {
+		from = models.TaskStatus{}
+	} else if err != nil {
+		return err
+	}
+	fromName := task.Status
+
+	if _, err := workflow.Apply(task, from, to, time.Now()); err != nil {
+		return err
+	}
+
+	return recordStatusChange(tx, task.ID, userID, fromName, status)
+}
+
+func recordStatusChange(tx *gorm.DB, taskID, userID uint, from, to string) error {
+	change := models.TaskStatusChange{
+		TaskID:     taskID,
+		UserID:     userID,
+		FromStatus: from,
+		ToStatus:   to,
+	}
+	return tx.Create(&change).Error
+}
+
+func respondStatusChangeError(c *gin.Context, err error) {
+	var transitionErr *workflow.TransitionError
+	if errors.As(err, &transitionErr) {
+		c.JSON(http.StatusConflict, gin.H{"error": transitionErr.Error()})
+		return
+	}
+	respondDefinitionError(c, err)
+}
+
+func GetTaskStatusHistory(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleViewer)
+	if err != nil {
+		respondAccessError(c, err, "Task not found")
+		return
+	}
+
+	var changes []models.TaskStatusChange
+	if err := database.DB.Where("task_id = ?", task.ID).Order("created_at asc, id asc").Find(&changes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":       task.Status,
+		"started_at":   task.StartedAt,
+		"completed_at": task.CompletedAt,
+		"changes":      changes,
+	})
+}
+
+// GetStatusTransitions lists the allowed status changes so clients can
+// disable moves the server would reject.
+func GetStatusTransitions(c *gin.Context) {
+	type transition struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+		Name string `json:"name"`
+	}
+
+	transitions := make([]transition, 0, len(workflow.Transitions))
+	for _, t := range workflow.Transitions {
+		transitions = append(transitions, transition{From: t.From, To: t.To, Name: t.Name})
+	}
+
+	c.JSON(http.StatusOK, transitions)
+}
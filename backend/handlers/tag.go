@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/database"
+	"taskmanager/models"
+)
+
+// GetTags lists the tags of the active space. Tags on a workspace's tasks
+// are held by the workspace owner.
+func GetTags(c *gin.Context) {
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tags []models.Tag
+	if err := database.DB.Where("user_id = ?", space.OwnerID).Order("name asc").Find(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// normalizeTagName trims whitespace and a leading '#' so that "#billing"
+// and "billing" name the same tag.
+func normalizeTagName(name string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+}
+
+// findOrCreateTags returns the user's tags with the given names, creating
+// any that don't exist yet. Blank and duplicate names are skipped.
+func findOrCreateTags(db *gorm.DB, userID uint, names []string) ([]models.Tag, error) {
+	var tags []models.Tag
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = normalizeTagName(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var tag models.Tag
+		if err := db.Where(models.Tag{UserID: userID, Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
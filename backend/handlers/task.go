@@ -1,15 +1,22 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/jinzhu/gorm"
 
 	"taskmanager/database"
+	"taskmanager/dates"
 	"taskmanager/models"
+	"taskmanager/workflow"
 )
 
 var upgrader = websocket.Upgrader{
@@ -20,19 +27,78 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-var clients = make(map[uint][]*websocket.Conn)
+// wsClient is one WebSocket connection of a user. gorilla/websocket allows
+// only one concurrent writer per connection, so writes go through write.
+type wsClient struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+func (cl *wsClient) write(messageType int, data []byte) error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.conn.WriteMessage(messageType, data)
+}
+
+var (
+	clientsMu sync.RWMutex
+	clients   = make(map[uint][]*wsClient)
+)
+
+func addClient(userID uint, cl *wsClient) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	clients[userID] = append(clients[userID], cl)
+}
+
+func removeClient(userID uint, cl *wsClient) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	clients[userID] = removeConnection(clients[userID], cl)
+	if len(clients[userID]) == 0 {
+		delete(clients, userID)
+	}
+}
+
+// userClients returns a copy of the user's connections, so they can be
+// written to without holding clientsMu.
+func userClients(userID uint) []*wsClient {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+	return append([]*wsClient(nil), clients[userID]...)
+}
+
+// TaskEvent is a structured notification pushed to a user's WebSocket
+// connections alongside the plain "update" refresh signal.
+type TaskEvent struct {
+	Type   string      `json:"type"`
+	TaskID uint        `json:"task_id,omitempty"`
+	Data   interface{} `json:"data,omitempty"`
+}
 
 func notifyTaskUpdate(userID uint) {
+	sendToUser(userID, []byte("update"))
+}
+
+func notifyTaskEvent(userID uint, event TaskEvent) {
+	message, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error encoding %s event for user %d: %v", event.Type, userID, err)
+		return
+	}
+	sendToUser(userID, message)
+}
+
+func sendToUser(userID uint, message []byte) {
 	log.Printf("Attempting to notify user %d about task update", userID)
 	
-	if connections, ok := clients[userID]; ok {
+	if connections := userClients(userID); len(connections) > 0 {
 		log.Printf("Found %d active connections for user %d", len(connections), userID)
 		
-		for i, conn := range connections {
-			message := []byte("update")
-			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+		for i, cl := range connections {
+			if err := cl.write(websocket.TextMessage, message); err != nil {
 				log.Printf("Error sending update to connection %d: %v", i, err)
-				conn.Close()
+				cl.conn.Close()
 			} else {
 				log.Printf("Successfully sent update to connection %d", i)
 			}
@@ -48,22 +114,16 @@ func TaskWebSocket(c *gin.Context) {
 	
 	log.Printf("WebSocket connection attempt from user %d with token: %s", userID, token)
 	
-	upgrader.CheckOrigin = func(r *http.Request) bool {
-		return true 
-	}
-	
 	ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("Error upgrading connection: %v", err)
 		return
 	}
 
-	if clients[userID] == nil {
-		clients[userID] = make([]*websocket.Conn, 0)
-	}
-	clients[userID] = append(clients[userID], ws)
+	cl := &wsClient{conn: ws}
+	addClient(userID, cl)
 	
-	if err := ws.WriteMessage(websocket.TextMessage, []byte("update")); err != nil {
+	if err := cl.write(websocket.TextMessage, []byte("update")); err != nil {
 		log.Printf("Error sending test message: %v", err)
 	} else {
 		log.Printf("Test message sent successfully to user %d", userID)
@@ -85,26 +145,26 @@ func TaskWebSocket(c *gin.Context) {
 			}
 			log.Printf("Received message from user %d: %s", userID, string(message))
 			
-			if err := ws.WriteMessage(messageType, message); err != nil {
+			if err := cl.write(messageType, message); err != nil {
 				log.Printf("Error echoing message to user %d: %v", userID, err)
 				break
 			}
 		}
 		
-		clients[userID] = removeConnection(clients[userID], ws)
+		removeClient(userID, cl)
 		ws.Close()
 		log.Printf("WebSocket read loop ended for user %d", userID)
 	}()
 
 	go func() {
 		<-c.Done()
-		clients[userID] = removeConnection(clients[userID], ws)
+		removeClient(userID, cl)
 		ws.Close()
 		log.Printf("WebSocket connection closed for user %d", userID)
 	}()
 }
 
-func removeConnection(connections []*websocket.Conn, conn *websocket.Conn) []*websocket.Conn {
+func removeConnection(connections []*wsClient, conn *wsClient) []*wsClient {
 	for i, c := range connections {
 		if c == conn {
 			return append(connections[:i], connections[i+1:]...)
@@ -113,132 +173,266 @@ func removeConnection(connections []*websocket.Conn, conn *websocket.Conn) []*we
 	return connections
 }
 
+func findUserTask(userID uint, taskID string) (models.Task, error) {
+	var task models.Task
+	err := database.DB.Where("id = ? AND user_id = ?", taskID, userID).First(&task).Error
+	return task, err
+}
+
+// GetTasks lists the tasks of the active space: the user's own, or the
+// workspace's.
 func GetTasks(c *gin.Context) {
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, err := filterTasks(c, space.scope(database.DB))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tasks []models.Task
+	if err := query.Preload("Tags").Preload("Assignees").Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// taskSortOrders maps the sort query parameter to an ORDER BY clause. Board
+// order is the default; sorting by due date puts undated tasks last either
+// way.
+var taskSortOrders = map[string]string{
+	"":          "rank asc, id asc",
+	"rank":      "rank asc, id asc",
+	"due_date":  "due_date asc nulls last, rank asc, id asc",
+	"-due_date": "due_date desc nulls last, rank asc, id asc",
+}
+
+// filterTasks applies the GetTasks query-string filters and sort order to
+// query.
+func filterTasks(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
 	userID := c.GetUint("userID")
-	status := c.Query("status")
-	priority := c.Query("priority")
-	dueDateStr := c.Query("due_date")
+	return applyTaskFilter(query, taskFilterFromQuery(c), userID, userLocation(userID))
+}
 
-	query := database.DB.Where("user_id = ?", userID)
+func taskFilterFromQuery(c *gin.Context) models.TaskFilter {
+	return models.TaskFilter{
+		Status:     c.Query("status"),
+		Priority:   c.Query("priority"),
+		DueDate:    c.Query("due_date"),
+		HasDueDate: c.Query("has_due_date"),
+		ProjectID:  c.Query("project_id"),
+		Tag:        c.Query("tag"),
+		Sort:       c.Query("sort"),
+		Assignee:   c.Query("assignee"),
+		Unassigned: c.Query("unassigned"),
+	}
+}
 
-	if status != "" {
-		query = query.Where("status = ?", status)
+// applyTaskFilter adds filter's conditions and sort order to query for
+// userID, who assignee=me refers to. Dates are read in loc. It only builds
+// the query, so it also serves to validate a filter before it is saved.
+func applyTaskFilter(query *gorm.DB, filter models.TaskFilter, userID uint, loc *time.Location) (*gorm.DB, error) {
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Priority != "" {
+		query = query.Where("priority = ?", filter.Priority)
+	}
+	if filter.DueDate != "" {
+		// Tasks due on or before the given day, in the user's timezone.
+		day, err := dates.ParseDay(filter.DueDate, loc)
+		if err != nil {
+			return nil, errors.New("Invalid due_date, expected YYYY-MM-DD")
+		}
+		query = query.Where("due_date < ?", dates.AddDays(day, 1))
+	}
+	if filter.HasDueDate != "" {
+		hasDueDate, err := strconv.ParseBool(filter.HasDueDate)
+		if err != nil {
+			return nil, errors.New("Invalid has_due_date, expected true or false")
+		}
+		if hasDueDate {
+			query = query.Where("due_date IS NOT NULL")
+		} else {
+			query = query.Where("due_date IS NULL")
+		}
+	}
+	if filter.ProjectID == "none" {
+		query = query.Where("project_id IS NULL")
+	} else if filter.ProjectID != "" {
+		projectID, err := strconv.ParseUint(filter.ProjectID, 10, 32)
+		if err != nil {
+			return nil, errors.New("Invalid project_id")
+		}
+		query = query.Where("project_id = ?", projectID)
 	}
-	if priority != "" {
-		query = query.Where("priority = ?", priority)
+	if filter.Tag != "" {
+		query = query.Where("id IN (SELECT task_tags.task_id FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE tags.name = ?)", filter.Tag)
 	}
-	if dueDateStr != "" {
-		dueDate, err := time.Parse("2006-01-02", dueDateStr)
-		if err == nil {
-			query = query.Where("due_date <= ?", dueDate)
+	if filter.Assignee == "me" {
+		query = query.Where("id IN (SELECT task_id FROM task_assignees WHERE user_id = ?)", userID)
+	} else if filter.Assignee != "" {
+		assigneeID, err := strconv.ParseUint(filter.Assignee, 10, 32)
+		if err != nil {
+			return nil, errors.New("Invalid assignee, expected a user ID or me")
+		}
+		query = query.Where("id IN (SELECT task_id FROM task_assignees WHERE user_id = ?)", assigneeID)
+	}
+	if filter.Unassigned != "" {
+		unassigned, err := strconv.ParseBool(filter.Unassigned)
+		if err != nil {
+			return nil, errors.New("Invalid unassigned, expected true or false")
+		}
+		if unassigned {
+			query = query.Where("id NOT IN (SELECT task_id FROM task_assignees)")
+		} else {
+			query = query.Where("id IN (SELECT task_id FROM task_assignees)")
 		}
 	}
 
-	var tasks []models.Task
-	if err := query.Find(&tasks).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	order, ok := taskSortOrders[filter.Sort]
+	if !ok {
+		return nil, errors.New("Invalid sort, expected rank, due_date or -due_date")
 	}
 
-	c.JSON(http.StatusOK, tasks)
+	return query.Order(order), nil
 }
 
 type TaskRequest struct {
 	Title       string `json:"title" binding:"required"`
 	Description string `json:"description"`
-	Status      string `json:"status" binding:"required,oneof=Pending In-Progress Completed"`
-	Priority    string `json:"priority" binding:"required,oneof=Low Medium High Critical"`
+	Status      string `json:"status" binding:"required,max=50"`
+	Priority    string `json:"priority" binding:"required,max=50"`
 	DueDate     string `json:"due_date"` 
+	ProjectID   *uint  `json:"project_id"`
+
+	EstimateHours *float64 `json:"estimate_hours" binding:"omitempty,min=0,max=1000"`
+	StoryPoints   *int     `json:"story_points" binding:"omitempty,min=0,max=1000"`
 }
 
 func CreateTask(c *gin.Context) {
 	userID := c.GetUint("userID")
 
+	if !requireWorkspaceRole(c, models.WorkspaceMember) {
+		return
+	}
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	var taskReq TaskRequest
 	if err := c.ShouldBindJSON(&taskReq); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var dueDate time.Time
-	var err error
-	if taskReq.DueDate != "" {
-		log.Printf("Attempting to parse date: '%s'", taskReq.DueDate)
-		
-		if len(taskReq.DueDate) == 10 && taskReq.DueDate[4] == '-' && taskReq.DueDate[7] == '-' {
-			dueDate, err = time.Parse("2006-01-02", taskReq.DueDate)
-			if err == nil {
-				log.Printf("Successfully parsed date as YYYY-MM-DD: %v", dueDate)
-			} else {
-				log.Printf("Failed to parse as YYYY-MM-DD: %v", err)
-			}
-		} else {
-			formats := []string{
-				"2006-01-02",           
-				"2006-01-02T15:04:05Z",  
-				"2006-01-02T15:04:05",  
-				"01/02/2006",           
-				"02-01-2006",           
-				time.RFC3339,            
-			}
+	if taskReq.ProjectID != nil {
+		if _, err := findActiveProject(space, *taskReq.ProjectID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Project not found or archived"})
+			return
+		}
+	}
 
-			parsed := false
-			for _, format := range formats {
-				dueDate, err = time.Parse(format, taskReq.DueDate)
-				if err == nil {
-					parsed = true
-					log.Printf("Successfully parsed date with format %s: %v", format, dueDate)
-					break
-				}
-			}
+	if err := validatePriority(space.OwnerID, taskReq.Priority); err != nil {
+		respondDefinitionError(c, err)
+		return
+	}
 
-			if !parsed {
-				if len(taskReq.DueDate) > 10 {
-					datePart := taskReq.DueDate[:10] 
-					dueDate, err = time.Parse("2006-01-02", datePart)
-					if err == nil {
-						parsed = true
-						log.Printf("Parsed date using first 10 chars: %v", dueDate)
-					}
-				}
-				
-				if !parsed {
-					log.Printf("Error parsing date '%s': %v", taskReq.DueDate, err)
-					log.Printf("Using current date instead")
-					dueDate = time.Now()
-				}
-			}
+	// A task created without a due date stays undated.
+	var dueDate *time.Time
+	var dueAllDay bool
+	if taskReq.DueDate != "" {
+		parsed, allDay, err := dates.Parse(taskReq.DueDate, userLocation(userID))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
-	} else {
-		dueDate = time.Now()
+		dueDate, dueAllDay = &parsed, allDay
 	}
 
 	task := models.Task{
-		UserID:      userID,
 		Title:       taskReq.Title,
 		Description: taskReq.Description,
-		Status:      taskReq.Status,
 		Priority:    taskReq.Priority,
 		DueDate:     dueDate,
+		DueAllDay:   dueAllDay,
+		ProjectID:   taskReq.ProjectID,
+
+		EstimateHours: taskReq.EstimateHours,
+		StoryPoints:   taskReq.StoryPoints,
+	}
+	space.place(&task)
+
+	// The task starts without a status so Apply records the initial one
+	// and stamps StartedAt or CompletedAt to match it.
+	status, err := findStatus(space.OwnerID, taskReq.Status)
+	if err != nil {
+		respondDefinitionError(c, err)
+		return
+	}
+	if _, err := workflow.Apply(&task, models.TaskStatus{}, status, time.Now()); err != nil {
+		respondStatusChangeError(c, err)
+		return
+	}
+
+	task.Rank, err = nextRankInColumn(database.DB, space, task.Status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	if err := tx.Create(&task).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := recordStatusChange(tx, task.ID, userID, "", task.Status); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := recordRevision(tx, userID, models.TaskSnapshot{}, task, nil); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifications, err := syncMentions(tx, task, nil, userID, task.Description)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	if err := database.DB.Create(&task).Error; err != nil {
+	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	notifyTaskUpdate(userID)
-	c.JSON(http.StatusCreated, task)
+	pushNotifications(notifications)
+	space.notify()
+	c.JSON(http.StatusCreated, withCapacityWarnings(userID, task))
 }
 
 func UpdateTask(c *gin.Context) {
 	userID := c.GetUint("userID")
 	taskID := c.Param("id")
 
-	var existingTask models.Task
-	result := database.DB.Where("id = ? AND user_id = ?", taskID, userID).First(&existingTask)
-	if result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found or you don't have permission"})
+	existingTask, err := findTaskFor(userID, taskID, models.RoleEditor)
+	if err != nil {
+		respondAccessError(c, err, "Task not found or you don't have permission")
 		return
 	}
 
@@ -248,81 +442,105 @@ func UpdateTask(c *gin.Context) {
 		return
 	}
 
-	var dueDate time.Time
-	var err error
-	if taskReq.DueDate != "" {
-		log.Printf("Attempting to parse date for update: '%s'", taskReq.DueDate)
-		
-		if len(taskReq.DueDate) == 10 && taskReq.DueDate[4] == '-' && taskReq.DueDate[7] == '-' {
-			dueDate, err = time.Parse("2006-01-02", taskReq.DueDate)
-			if err == nil {
-				log.Printf("Successfully parsed date as YYYY-MM-DD: %v", dueDate)
-			} else {
-				log.Printf("Failed to parse as YYYY-MM-DD: %v", err)
-			}
-		} else {
-			formats := []string{
-				"2006-01-02",           
-				"2006-01-02T15:04:05Z",  
-				"2006-01-02T15:04:05",   
-				"01/02/2006",           
-				"02-01-2006",           
-				time.RFC3339,            
-			}
+	before := existingTask.Snapshot()
 
-			parsed := false
-			for _, format := range formats {
-				dueDate, err = time.Parse(format, taskReq.DueDate)
-				if err == nil {
-					parsed = true
-					log.Printf("Successfully parsed date with format %s: %v", format, dueDate)
-					break
-				}
-			}
+	if taskReq.ProjectID != nil && (existingTask.ProjectID == nil || *existingTask.ProjectID != *taskReq.ProjectID) {
+		if !canChangeProject(userID, existingTask) {
+			c.JSON(http.StatusForbidden, gin.H{"error": errOwnerOnly.Error()})
+			return
+		}
+		if _, err := findActiveProject(spaceOf(existingTask.UserID, existingTask.WorkspaceID), *taskReq.ProjectID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Project not found or archived"})
+			return
+		}
+		existingTask.ProjectID = taskReq.ProjectID
+	}
 
-			if !parsed {
-				if len(taskReq.DueDate) > 10 {
-					datePart := taskReq.DueDate[:10] 
-					dueDate, err = time.Parse("2006-01-02", datePart)
-					if err == nil {
-						parsed = true
-						log.Printf("Parsed date using first 10 chars: %v", dueDate)
-					}
-				}
-				
-				if !parsed {
-					log.Printf("Error parsing date '%s': %v", taskReq.DueDate, err)
-					dueDate = existingTask.DueDate
-					log.Printf("Using existing due date: %v", dueDate)
-				}
-			}
+	if taskReq.Priority != existingTask.Priority {
+		if err := validatePriority(existingTask.UserID, taskReq.Priority); err != nil {
+			respondDefinitionError(c, err)
+			return
 		}
-	} else {
-		dueDate = existingTask.DueDate
+	}
+
+	// PUT keeps the due date when it is omitted; PATCH can clear it.
+	dueDate, dueAllDay := existingTask.DueDate, existingTask.DueAllDay
+	if taskReq.DueDate != "" {
+		parsed, allDay, err := dates.Parse(taskReq.DueDate, userLocation(userID))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		dueDate, dueAllDay = &parsed, allDay
+	}
+
+	if taskReq.Status != existingTask.Status {
+		rank, err := nextRankInColumn(database.DB, spaceOf(existingTask.UserID, existingTask.WorkspaceID), taskReq.Status)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		existingTask.Rank = rank
 	}
 
 	existingTask.Title = taskReq.Title
 	existingTask.Description = taskReq.Description
-	existingTask.Status = taskReq.Status
 	existingTask.Priority = taskReq.Priority
 	existingTask.DueDate = dueDate
+	existingTask.DueAllDay = dueAllDay
+	if taskReq.EstimateHours != nil {
+		existingTask.EstimateHours = taskReq.EstimateHours
+	}
+	if taskReq.StoryPoints != nil {
+		existingTask.StoryPoints = taskReq.StoryPoints
+	}
+
+	tx := database.DB.Begin()
+
+	if err := changeTaskStatus(tx, &existingTask, taskReq.Status, userID); err != nil {
+		tx.Rollback()
+		respondStatusChangeError(c, err)
+		return
+	}
+
+	if err := tx.Save(&existingTask).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := database.DB.Save(&existingTask).Error; err != nil {
+	if err := recordRevision(tx, userID, before, existingTask, nil); err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	notifyTaskUpdate(userID)
-	c.JSON(http.StatusOK, existingTask)
+	var notifications []models.Notification
+	if existingTask.Description != before.Description {
+		if notifications, err = syncMentions(tx, existingTask, nil, userID, existingTask.Description); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pushNotifications(notifications)
+	notifyCollaborators(existingTask)
+	c.JSON(http.StatusOK, withCapacityWarnings(userID, existingTask))
 }
 
 func DeleteTask(c *gin.Context) {
 	userID := c.GetUint("userID")
 	taskID := c.Param("id")
 
-	var task models.Task
-	if err := database.DB.Where("id = ? AND user_id = ?", taskID, userID).First(&task).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	task, err := findTaskFor(userID, taskID, roleOwner)
+	if err != nil {
+		respondAccessError(c, err, "Task not found")
 		return
 	}
 
@@ -331,6 +549,6 @@ func DeleteTask(c *gin.Context) {
 		return
 	}
 
-	notifyTaskUpdate(userID)
-	c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
+	notifyCollaborators(task)
+	c.JSON(http.StatusOK, gin.H{"message": "Task moved to trash"})
 }
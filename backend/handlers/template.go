@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/database"
+	"taskmanager/dates"
+	"taskmanager/models"
+)
+
+// templateVariable matches a {{name}} placeholder, allowing spaces inside
+// the braces.
+var templateVariable = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+type TemplateSubtaskRequest struct {
+	Title         string `json:"title" binding:"required"`
+	Description   string `json:"description"`
+	Priority      string `json:"priority" binding:"max=50"`
+	DueOffsetDays *int   `json:"due_offset_days" binding:"omitempty,min=-3650,max=3650"`
+}
+
+type TemplateRequest struct {
+	Name          string                   `json:"name" binding:"required,max=100"`
+	Title         string                   `json:"title" binding:"required"`
+	Description   string                   `json:"description"`
+	Priority      string                   `json:"priority" binding:"max=50"`
+	DueOffsetDays *int                     `json:"due_offset_days" binding:"omitempty,min=-3650,max=3650"`
+	Subtasks      []TemplateSubtaskRequest `json:"subtasks" binding:"max=100,dive"`
+}
+
+type InstantiateTemplateRequest struct {
+	Variables map[string]string `json:"variables"`
+	Status    string            `json:"status"`
+	ProjectID *uint             `json:"project_id"`
+	// StartDate is the day due date offsets count from, YYYY-MM-DD in the
+	// user's timezone. It defaults to today.
+	StartDate string `json:"start_date"`
+}
+
+// TemplateResponse is a template along with the variables its text uses.
+type TemplateResponse struct {
+	models.TaskTemplate
+	Variables []string `json:"variables"`
+}
+
+func findUserTemplate(userID uint, templateID interface{}) (models.TaskTemplate, error) {
+	var template models.TaskTemplate
+	err := database.DB.Where("id = ? AND user_id = ?", templateID, userID).
+		Preload("Subtasks", func(db *gorm.DB) *gorm.DB { return db.Order("position asc, id asc") }).
+		First(&template).Error
+	return template, err
+}
+
+// templateVariables returns the distinct variable names used anywhere in a
+// template, sorted.
+func templateVariables(template models.TaskTemplate) []string {
+	texts := []string{template.Title, template.Description}
+	for _, subtask := range template.Subtasks {
+		texts = append(texts, subtask.Title, subtask.Description)
+	}
+
+	seen := make(map[string]bool)
+	variables := []string{}
+	for _, text := range texts {
+		for _, match := range templateVariable.FindAllStringSubmatch(text, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				variables = append(variables, match[1])
+			}
+		}
+	}
+	sort.Strings(variables)
+	return variables
+}
+
+func newTemplateResponse(template models.TaskTemplate) TemplateResponse {
+	return TemplateResponse{TaskTemplate: template, Variables: templateVariables(template)}
+}
+
+func substituteVariables(text string, variables map[string]string) string {
+	return templateVariable.ReplaceAllStringFunc(text, func(placeholder string) string {
+		return variables[templateVariable.FindStringSubmatch(placeholder)[1]]
+	})
+}
+
+// validateTemplatePriorities checks every priority a template request
+// names; blank ones fall back to the user's default when instantiated.
+func validateTemplatePriorities(userID uint, req TemplateRequest) error {
+	names := []string{req.Priority}
+	for _, subtask := range req.Subtasks {
+		names = append(names, subtask.Priority)
+	}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if err := validatePriority(userID, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameTemplatePriority follows a priority rename in the user's templates
+// and their subtasks. An empty newName clears the priority, so they fall
+// back to the user's default.
+func renameTemplatePriority(tx *gorm.DB, userID uint, oldName, newName string) error {
+	if err := tx.Unscoped().Model(&models.TaskTemplate{}).
+		Where("user_id = ? AND priority = ?", userID, oldName).
+		UpdateColumn("priority", newName).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.TaskTemplateSubtask{}).
+		Where("priority = ? AND template_id IN (?)", oldName, tx.Unscoped().Table("task_templates").Select("id").Where("user_id = ?", userID).QueryExpr()).
+		UpdateColumn("priority", newName).Error
+}
+
+// saveTemplate writes a template and replaces its subtasks with those in
+// req, in the order given.
+func saveTemplate(template *models.TaskTemplate, req TemplateRequest) error {
+	template.Name = req.Name
+	template.Title = req.Title
+	template.Description = req.Description
+	template.Priority = req.Priority
+	template.DueOffsetDays = req.DueOffsetDays
+
+	tx := database.DB.Begin()
+
+	if err := tx.Save(template).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Where("template_id = ?", template.ID).Delete(&models.TaskTemplateSubtask{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	template.Subtasks = make([]models.TaskTemplateSubtask, 0, len(req.Subtasks))
+	for i, item := range req.Subtasks {
+		subtask := models.TaskTemplateSubtask{
+			TemplateID:    template.ID,
+			Position:      i,
+			Title:         item.Title,
+			Description:   item.Description,
+			Priority:      item.Priority,
+			DueOffsetDays: item.DueOffsetDays,
+		}
+		if err := tx.Create(&subtask).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		template.Subtasks = append(template.Subtasks, subtask)
+	}
+
+	return tx.Commit().Error
+}
+
+func GetTemplates(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var templates []models.TaskTemplate
+	if err := database.DB.Where("user_id = ?", userID).
+		Preload("Subtasks", func(db *gorm.DB) *gorm.DB { return db.Order("position asc, id asc") }).
+		Order("name asc").
+		Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	responses := make([]TemplateResponse, len(templates))
+	for i, template := range templates {
+		responses[i] = newTemplateResponse(template)
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+func GetTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	template, err := findUserTemplate(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newTemplateResponse(template))
+}
+
+func CreateTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var templateReq TemplateRequest
+	if err := c.ShouldBindJSON(&templateReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateTemplatePriorities(userID, templateReq); err != nil {
+		respondDefinitionError(c, err)
+		return
+	}
+
+	template := models.TaskTemplate{UserID: userID}
+	if err := saveTemplate(&template, templateReq); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newTemplateResponse(template))
+}
+
+// UpdateTemplate replaces a template, including its list of subtasks.
+func UpdateTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	template, err := findUserTemplate(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	var templateReq TemplateRequest
+	if err := c.ShouldBindJSON(&templateReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateTemplatePriorities(userID, templateReq); err != nil {
+		respondDefinitionError(c, err)
+		return
+	}
+
+	if err := saveTemplate(&template, templateReq); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newTemplateResponse(template))
+}
+
+func DeleteTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	template, err := findUserTemplate(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	tx := database.DB.Begin()
+
+	if err := tx.Where("template_id = ?", template.ID).Delete(&models.TaskTemplateSubtask{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Unscoped().Delete(&template).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template deleted successfully"})
+}
+
+// InstantiateTemplate creates real tasks from a template: the main task
+// and then one task per subtask, all in one transaction and in the given
+// status and project. Every variable the template uses must be supplied.
+// Due dates are all-day, offset from start_date.
+func InstantiateTemplate(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	if !requireWorkspaceRole(c, models.WorkspaceMember) {
+		return
+	}
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := findUserTemplate(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	var req InstantiateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	missing := []string{}
+	for _, name := range templateVariables(template) {
+		if strings.TrimSpace(req.Variables[name]) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing template variables", "missing": missing})
+		return
+	}
+
+	loc := userLocation(userID)
+	start := dates.StartOfDay(time.Now(), loc)
+	if req.StartDate != "" {
+		start, err = dates.ParseDay(req.StartDate, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start_date must be YYYY-MM-DD"})
+			return
+		}
+	}
+
+	if req.ProjectID != nil {
+		if _, err := findActiveProject(space, *req.ProjectID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Project not found or archived"})
+			return
+		}
+	}
+
+	lookups, err := loadImportLookups(space, loc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	status := lookups.defaultStatus
+	if req.Status != "" {
+		if status, err = findStatus(space.OwnerID, req.Status); err != nil {
+			respondDefinitionError(c, err)
+			return
+		}
+	}
+
+	newTask := func(title, description, priority string, dueOffsetDays *int) (importedTask, error) {
+		if priority == "" {
+			priority = lookups.defaultPriority
+		} else if !lookups.priorities[priority] {
+			return importedTask{}, fmt.Errorf("%w %q", errUnknownPriority, priority)
+		}
+
+		task := models.Task{
+			Title:       substituteVariables(title, req.Variables),
+			Description: substituteVariables(description, req.Variables),
+			Priority:    priority,
+			ProjectID:   req.ProjectID,
+		}
+		if dueOffsetDays != nil {
+			due := dates.AddDays(start, *dueOffsetDays)
+			task.DueDate, task.DueAllDay = &due, true
+		}
+		return importedTask{task: task, status: status}, nil
+	}
+
+	tasks := make([]importedTask, 0, len(template.Subtasks)+1)
+	main, err := newTask(template.Title, template.Description, template.Priority, template.DueOffsetDays)
+	if err != nil {
+		respondDefinitionError(c, err)
+		return
+	}
+	tasks = append(tasks, main)
+	for _, subtask := range template.Subtasks {
+		task, err := newTask(subtask.Title, subtask.Description, subtask.Priority, subtask.DueOffsetDays)
+		if err != nil {
+			respondDefinitionError(c, err)
+			return
+		}
+		tasks = append(tasks, task)
+	}
+
+	taskIDs, err := createImportedTasks(space, userID, tasks)
+	if err != nil {
+		respondStatusChangeError(c, err)
+		return
+	}
+
+	var created []models.Task
+	if err := database.DB.Where("id IN (?)", taskIDs).Order("id asc").Find(&created).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	space.notify()
+	c.JSON(http.StatusCreated, gin.H{"template_id": template.ID, "tasks": created})
+}
@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+
+	"taskmanager/database"
+	"taskmanager/dates"
+	"taskmanager/models"
+)
+
+// trackedSeconds sums entry durations, counting running timers up to now.
+const trackedSeconds = "COALESCE(SUM(EXTRACT(EPOCH FROM (COALESCE(stopped_at, NOW()) - started_at))), 0)::bigint"
+
+type TimerRequest struct {
+	Note string `json:"note" binding:"max=1000"`
+}
+
+type TimeEntryRequest struct {
+	StartedAt time.Time  `json:"started_at" binding:"required"`
+	StoppedAt *time.Time `json:"stopped_at"`
+	Note      string     `json:"note" binding:"max=1000"`
+}
+
+type taskTimeTotal struct {
+	TaskID  uint  `json:"task_id"`
+	Seconds int64 `json:"seconds"`
+}
+
+type dayTimeTotal struct {
+	Date    string `json:"date"`
+	Seconds int64  `json:"seconds"`
+}
+
+func runningTimer(userID uint) (*models.TimeEntry, error) {
+	var entries []models.TimeEntry
+	if err := database.DB.Where("user_id = ? AND stopped_at IS NULL", userID).Limit(1).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	return &entries[0], nil
+}
+
+func GetRunningTimer(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	entry, err := runningTimer(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"timer": entry})
+}
+
+// StartTimer starts a timer on a task. Any timer the user already has
+// running is stopped first, so there is never more than one.
+func StartTimer(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleEditor)
+	if err != nil {
+		respondAccessError(c, err, "Task not found")
+		return
+	}
+
+	// The body is optional; a bare POST starts or stops without a note.
+	var timerReq TimerRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&timerReq); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	now := time.Now()
+	tx := database.DB.Begin()
+
+	var running []models.TimeEntry
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").
+		Where("user_id = ? AND stopped_at IS NULL", userID).
+		Find(&running).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for i := range running {
+		running[i].Stop(now)
+		if err := tx.Save(&running[i]).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	entry := models.TimeEntry{
+		TaskID:    task.ID,
+		UserID:    userID,
+		StartedAt: now,
+		Note:      timerReq.Note,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		tx.Rollback()
+		// The partial unique index on running entries rejects a timer
+		// started concurrently by another request.
+		if isUniqueViolation(err, "idx_time_entries_running") {
+			c.JSON(http.StatusConflict, gin.H{"error": "Another timer was started at the same time"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, stopped := range running {
+		notifyTaskEvent(userID, TaskEvent{Type: "timer.stopped", TaskID: stopped.TaskID, Data: stopped})
+	}
+	notifyTaskEvent(userID, TaskEvent{Type: "timer.started", TaskID: task.ID, Data: entry})
+	c.JSON(http.StatusCreated, entry)
+}
+
+func StopTimer(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	// The body is optional; a bare POST starts or stops without a note.
+	var timerReq TimerRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&timerReq); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	entry, err := runningTimer(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if entry == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No timer is running"})
+		return
+	}
+
+	entry.Stop(time.Now())
+	if timerReq.Note != "" {
+		entry.Note = timerReq.Note
+	}
+
+	if err := database.DB.Save(entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyTaskEvent(userID, TaskEvent{Type: "timer.stopped", TaskID: entry.TaskID, Data: entry})
+	c.JSON(http.StatusOK, entry)
+}
+
+func GetTaskTimeEntries(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleViewer)
+	if err != nil {
+		respondAccessError(c, err, "Task not found")
+		return
+	}
+
+	var entries []models.TimeEntry
+	if err := database.DB.Where("task_id = ? AND user_id = ?", task.ID, userID).Order("started_at desc").Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var totalSeconds int64
+	if err := database.DB.Model(&models.TimeEntry{}).
+		Select(trackedSeconds).
+		Where("task_id = ? AND user_id = ?", task.ID, userID).
+		Row().
+		Scan(&totalSeconds); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":       entries,
+		"total_seconds": totalSeconds,
+	})
+}
+
+// CreateTimeEntry records time that wasn't tracked with a timer. Manual
+// entries must have both ends; use StartTimer for a running one.
+func CreateTimeEntry(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	task, err := findTaskFor(userID, c.Param("id"), models.RoleEditor)
+	if err != nil {
+		respondAccessError(c, err, "Task not found")
+		return
+	}
+
+	var entryReq TimeEntryRequest
+	if err := c.ShouldBindJSON(&entryReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if entryReq.StoppedAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stopped_at is required for manual entries"})
+		return
+	}
+	if !entryReq.StoppedAt.After(entryReq.StartedAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stopped_at must be after started_at"})
+		return
+	}
+
+	entry := models.TimeEntry{
+		TaskID:    task.ID,
+		UserID:    userID,
+		StartedAt: entryReq.StartedAt,
+		Note:      entryReq.Note,
+	}
+	entry.Stop(*entryReq.StoppedAt)
+
+	if err := database.DB.Create(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyTaskEvent(userID, TaskEvent{Type: "time_entry.created", TaskID: task.ID, Data: entry})
+	c.JSON(http.StatusCreated, entry)
+}
+
+// UpdateTimeEntry edits an entry's times or note. A running timer may keep
+// running by omitting stopped_at; a stopped entry can't be restarted.
+func UpdateTimeEntry(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var entry models.TimeEntry
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&entry).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Time entry not found"})
+		return
+	}
+
+	var entryReq TimeEntryRequest
+	if err := c.ShouldBindJSON(&entryReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if entryReq.StoppedAt == nil && !entry.Running() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stopped_at is required for a stopped entry"})
+		return
+	}
+	if entryReq.StoppedAt != nil && !entryReq.StoppedAt.After(entryReq.StartedAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stopped_at must be after started_at"})
+		return
+	}
+	if entryReq.StoppedAt == nil && entryReq.StartedAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A running timer can't start in the future"})
+		return
+	}
+
+	wasRunning := entry.Running()
+	entry.StartedAt = entryReq.StartedAt
+	entry.Note = entryReq.Note
+	if entryReq.StoppedAt != nil {
+		entry.Stop(*entryReq.StoppedAt)
+	}
+
+	if err := database.DB.Save(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if wasRunning && !entry.Running() {
+		notifyTaskEvent(userID, TaskEvent{Type: "timer.stopped", TaskID: entry.TaskID, Data: entry})
+	} else {
+		notifyTaskEvent(userID, TaskEvent{Type: "time_entry.updated", TaskID: entry.TaskID, Data: entry})
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+func DeleteTimeEntry(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var entry models.TimeEntry
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&entry).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Time entry not found"})
+		return
+	}
+
+	if err := database.DB.Delete(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	eventType := "time_entry.deleted"
+	if entry.Running() {
+		eventType = "timer.stopped"
+	}
+	notifyTaskEvent(userID, TaskEvent{Type: eventType, TaskID: entry.TaskID, Data: gin.H{"id": entry.ID}})
+	c.JSON(http.StatusOK, gin.H{"message": "Time entry deleted successfully"})
+}
+
+// GetTimeTotals sums tracked time per task and per day between the from and
+// to dates (inclusive, defaulting to the last seven days). Entries count
+// towards the day they started on in the user's timezone.
+func GetTimeTotals(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	loc := userLocation(userID)
+	from, to, err := parseDateRange(c, 7, maxDailyRangeDays, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries := database.DB.Model(&models.TimeEntry{}).
+		Where("user_id = ? AND started_at >= ? AND started_at < ?", userID, from, to)
+
+	byTask := []taskTimeTotal{}
+	if err := entries.Select("task_id, " + trackedSeconds + " AS seconds").
+		Group("task_id").
+		Order("seconds desc").
+		Scan(&byTask).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var dayRows []struct {
+		Day     time.Time
+		Seconds int64
+	}
+	if err := entries.Select("DATE(started_at AT TIME ZONE ?) AS day, "+trackedSeconds+" AS seconds", loc.String()).
+		Group("day").
+		Order("day asc").
+		Scan(&dayRows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	byDay := make([]dayTimeTotal, 0, len(dayRows))
+	var total int64
+	for _, row := range dayRows {
+		byDay = append(byDay, dayTimeTotal{Date: row.Day.Format("2006-01-02"), Seconds: row.Seconds})
+		total += row.Seconds
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":          from.Format("2006-01-02"),
+		"to":            dates.AddDays(to, -1).Format("2006-01-02"),
+		"by_task":       byTask,
+		"by_day":        byDay,
+		"total_seconds": total,
+	})
+}
+
+// isUniqueViolation reports whether err is Postgres rejecting a row because
+// it would duplicate an entry in the named unique index.
+func isUniqueViolation(err error, index string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == index
+}
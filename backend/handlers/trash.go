@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/database"
+	"taskmanager/models"
+)
+
+// Deleted tasks stay in the trash, soft-deleted through gorm.Model's
+// DeletedAt, until they are restored or purged. Purging removes the task and
+// everything hanging off it for good.
+
+// DefaultTrashRetention is how long a deleted task is kept before the
+// scheduled purge removes it.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+func findTrashedTask(space taskSpace, taskID string) (models.Task, error) {
+	var task models.Task
+	err := space.scope(database.DB.Unscoped()).
+		Where("id = ? AND deleted_at IS NOT NULL", taskID).
+		First(&task).Error
+	return task, err
+}
+
+// GetTrash lists the deleted tasks of the active space, most recently
+// deleted first.
+func GetTrash(c *gin.Context) {
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := space.scope(database.DB.Unscoped().Model(&models.Task{})).
+		Where("deleted_at IS NOT NULL")
+
+	pagination := parsePagination(c)
+	if err := query.Count(&pagination.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tasks []models.Task
+	if err := query.Preload("Tags").
+		Order("deleted_at desc").
+		Offset(pagination.Offset()).
+		Limit(pagination.PageSize).
+		Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tasks":      tasks,
+		"pagination": pagination,
+	})
+}
+
+// RestoreTask takes a task out of the trash. It goes to the end of its
+// status column, since its old neighbours may have moved in the meantime.
+// The restore is recorded in the task's history. In a workspace only admins can restore tasks, as only they delete them.
+func RestoreTask(c *gin.Context) {
+	if !requireWorkspaceRole(c, models.WorkspaceAdmin) {
+		return
+	}
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := findTrashedTask(space, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found in trash"})
+		return
+	}
+
+	rank, err := nextRankInColumn(database.DB, spaceOf(task.UserID, task.WorkspaceID), task.Status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := database.DB.Begin()
+	if err := tx.Unscoped().Model(&task).Updates(map[string]interface{}{
+		"deleted_at": nil,
+		"rank":       rank,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	task.DeletedAt = nil
+	task.Rank = rank
+
+	revision := models.TaskRevision{
+		TaskID:   task.ID,
+		UserID:   c.GetUint("userID"),
+		Changes:  models.FieldChanges{},
+		Snapshot: task.Snapshot(),
+		Restored: true,
+	}
+	if err := tx.Create(&revision).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyCollaborators(task)
+	c.JSON(http.StatusOK, task)
+}
+
+// PurgeTask permanently deletes a task that is already in the trash.
+func PurgeTask(c *gin.Context) {
+	if !requireWorkspaceRole(c, models.WorkspaceAdmin) {
+		return
+	}
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := findTrashedTask(space, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found in trash"})
+		return
+	}
+
+	if err := purgeTask(task.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task permanently deleted"})
+}
+
+// purgeTask hard-deletes a task along with its comments, time entries,
+// status history, revisions, tags, assignees, shares, mentions and
+// notifications, then its attachments and their blobs.
+func purgeTask(taskID uint) error {
+	tx := database.DB.Begin()
+
+	steps := []func() error{
+		func() error {
+			return tx.Exec("DELETE FROM comment_revisions WHERE comment_id IN (SELECT id FROM comments WHERE task_id = ?)", taskID).Error
+		},
+		func() error {
+			return tx.Unscoped().Where("task_id = ?", taskID).Delete(&models.Comment{}).Error
+		},
+		func() error {
+			return tx.Unscoped().Where("task_id = ?", taskID).Delete(&models.TimeEntry{}).Error
+		},
+		func() error {
+			return tx.Where("task_id = ?", taskID).Delete(&models.TaskStatusChange{}).Error
+		},
+		func() error {
+			return tx.Where("task_id = ?", taskID).Delete(&models.TaskRevision{}).Error
+		},
+		func() error {
+			return tx.Exec("DELETE FROM task_tags WHERE task_id = ?", taskID).Error
+		},
+		func() error {
+			return tx.Exec("DELETE FROM task_assignees WHERE task_id = ?", taskID).Error
+		},
+		func() error {
+			return tx.Where("task_id = ?", taskID).Delete(&models.CalendarObject{}).Error
+		},
+		func() error {
+			return tx.Where("task_id = ?", taskID).Delete(&models.Share{}).Error
+		},
+		func() error {
+			return tx.Where("task_id = ?", taskID).Delete(&models.Mention{}).Error
+		},
+		func() error {
+			return tx.Where("task_id = ?", taskID).Delete(&models.Notification{}).Error
+		},
+		func() error {
+			return tx.Unscoped().Where("id = ?", taskID).Delete(&models.Task{}).Error
+		},
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	return deleteTaskAttachments(taskID)
+}
+
+// PurgeExpiredTasks permanently deletes every task that has been in the
+// trash for longer than retention, returning how many were removed.
+func PurgeExpiredTasks(retention time.Duration) (int, error) {
+	var ids []uint
+	if err := database.DB.Unscoped().Model(&models.Task{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", time.Now().Add(-retention)).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		if err := purgeTask(id); err != nil {
+			log.Printf("Error purging task %d: %v", id, err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// ScheduleTrashPurge runs PurgeExpiredTasks once at startup and then every
+// interval for the life of the process.
+func ScheduleTrashPurge(retention, interval time.Duration) {
+	purge := func() {
+		purged, err := PurgeExpiredTasks(retention)
+		if err != nil {
+			log.Printf("Error purging expired tasks: %v", err)
+			return
+		}
+		if purged > 0 {
+			log.Printf("Purged %d tasks deleted more than %s ago", purged, retention)
+		}
+	}
+
+	go func() {
+		purge()
+		for range time.Tick(interval) {
+			purge()
+		}
+	}()
+}
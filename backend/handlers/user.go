@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/database"
+	"taskmanager/dates"
+)
+
+type UserSettingsRequest struct {
+	DailyCapacityHours *float64 `json:"daily_capacity_hours" binding:"omitempty,min=0,max=24"`
+	Timezone           *string  `json:"timezone" binding:"omitempty,max=64"`
+}
+
+// userLocation returns the user's configured timezone, or UTC if it can't
+// be loaded.
+func userLocation(userID uint) *time.Location {
+	user, err := findUser(userID)
+	if err != nil {
+		log.Printf("Error loading timezone for user %d: %v", userID, err)
+		return time.UTC
+	}
+	return dates.LoadLocation(user.Timezone)
+}
+
+func GetCurrentUser(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	user, err := findUser(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateUserSettings changes the current user's preferences. Fields left out
+// of the request keep their current value.
+func UpdateUserSettings(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	user, err := findUser(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var settingsReq UserSettingsRequest
+	if err := c.ShouldBindJSON(&settingsReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if settingsReq.DailyCapacityHours != nil {
+		user.DailyCapacityHours = *settingsReq.DailyCapacityHours
+	}
+	if settingsReq.Timezone != nil {
+		if _, err := time.LoadLocation(*settingsReq.Timezone); err != nil || *settingsReq.Timezone == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown timezone, expected an IANA name such as America/New_York"})
+			return
+		}
+		user.Timezone = *settingsReq.Timezone
+	}
+
+	if err := database.DB.Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
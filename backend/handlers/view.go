@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/database"
+	"taskmanager/models"
+)
+
+type ViewRequest struct {
+	Name      string            `json:"name" binding:"required,max=100"`
+	Filter    models.TaskFilter `json:"filter"`
+	IsDefault bool              `json:"is_default"`
+}
+
+func findUserView(userID uint, viewID interface{}) (models.SavedView, error) {
+	var view models.SavedView
+	err := database.DB.Where("id = ? AND user_id = ?", viewID, userID).First(&view).Error
+	return view, err
+}
+
+// findDefaultView returns the user's default view, or nil if they haven't
+// chosen one.
+func findDefaultView(userID uint) (*models.SavedView, error) {
+	var view models.SavedView
+	err := database.DB.Where("user_id = ? AND is_default = ?", userID, true).First(&view).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// saveView writes a view. Making it the default takes that mark off the
+// user's other views in the same transaction.
+func saveView(view *models.SavedView) error {
+	tx := database.DB.Begin()
+
+	if view.IsDefault {
+		query := tx.Model(&models.SavedView{}).Where("user_id = ? AND is_default = ?", view.UserID, true)
+		if view.ID != 0 {
+			query = query.Where("id <> ?", view.ID)
+		}
+		if err := query.UpdateColumn("is_default", false).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Save(view).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// definitionTables maps the view filter keys that name a definition to
+// the table the definitions live in.
+var definitionTables = map[string]string{
+	"status":   "task_statuses",
+	"priority": "task_priorities",
+}
+
+// renameViewFilter follows a rename of one of ownerID's statuses or
+// priorities in the saved views that filter on it: field is the filter
+// key, "status" or "priority". An empty newName drops the filter, for a
+// deleted value nothing replaces.
+//
+// Besides the owner's views, this covers those of the members of the
+// owner's workspaces, which use the owner's definitions. Views don't
+// record which space they were made for, so a member who has a personal
+// definition of the same name keeps their views as they are.
+func renameViewFilter(tx *gorm.DB, ownerID uint, field, oldName, newName string) error {
+	members := tx.Table("memberships").Select("memberships.user_id").
+		Joins("JOIN workspaces ON workspaces.id = memberships.workspace_id AND workspaces.deleted_at IS NULL").
+		Where("workspaces.owner_id = ?", ownerID).
+		Where("NOT EXISTS (SELECT 1 FROM "+definitionTables[field]+" d WHERE d.user_id = memberships.user_id AND d.name = ?)", oldName)
+
+	update := gorm.Expr("jsonb_set(filter, ?::text[], to_jsonb(?::text))", "{"+field+"}", newName)
+	if newName == "" {
+		update = gorm.Expr("filter - ?::text", field)
+	}
+	return tx.Model(&models.SavedView{}).
+		Where("user_id = ? OR user_id IN (?)", ownerID, members.QueryExpr()).
+		Where("filter->>? = ?", field, oldName).
+		UpdateColumn("filter", update).Error
+}
+
+func GetViews(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var views []models.SavedView
+	if err := database.DB.Where("user_id = ?", userID).Order("name asc").Find(&views).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, views)
+}
+
+func GetView(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	view, err := findUserView(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "View not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// CreateView saves a filter under a name. The filter takes the same values
+// as the GET /tasks query parameters and is checked the same way.
+func CreateView(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var viewReq ViewRequest
+	if err := c.ShouldBindJSON(&viewReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := applyTaskFilter(database.DB, viewReq.Filter, userID, userLocation(userID)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	view := models.SavedView{
+		UserID:    userID,
+		Name:      viewReq.Name,
+		Filter:    viewReq.Filter,
+		IsDefault: viewReq.IsDefault,
+	}
+	if err := saveView(&view); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, view)
+}
+
+func UpdateView(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	view, err := findUserView(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "View not found"})
+		return
+	}
+
+	var viewReq ViewRequest
+	if err := c.ShouldBindJSON(&viewReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := applyTaskFilter(database.DB, viewReq.Filter, userID, userLocation(userID)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	view.Name = viewReq.Name
+	view.Filter = viewReq.Filter
+	view.IsDefault = viewReq.IsDefault
+	if err := saveView(&view); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+func DeleteView(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	view, err := findUserView(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "View not found"})
+		return
+	}
+
+	if err := database.DB.Delete(&view).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "View deleted successfully"})
+}
+
+// GetViewTasks runs a saved view in the active space, returning the same
+// list GET /tasks would for its filter.
+func GetViewTasks(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	view, err := findUserView(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "View not found"})
+		return
+	}
+
+	space, err := activeSpace(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, err := applyTaskFilter(space.scope(database.DB), view.Filter, userID, userLocation(userID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tasks []models.Task
+	if err := query.Preload("Tags").Preload("Assignees").Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
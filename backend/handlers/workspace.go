@@ -0,0 +1,484 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+
+	"taskmanager/database"
+	"taskmanager/models"
+)
+
+var workspaceRoleLevels = map[string]int{
+	models.WorkspaceGuest:  1,
+	models.WorkspaceMember: 2,
+	models.WorkspaceAdmin:  3,
+	models.WorkspaceOwner:  4,
+}
+
+// workspaceTaskRoles maps a workspace role to the role it grants on the
+// workspace's tasks and projects.
+var workspaceTaskRoles = map[string]string{
+	models.WorkspaceOwner:  roleOwner,
+	models.WorkspaceAdmin:  roleOwner,
+	models.WorkspaceMember: models.RoleEditor,
+	models.WorkspaceGuest:  models.RoleViewer,
+}
+
+var errOwnerMembership = errors.New("The workspace owner can't be changed or removed")
+
+type WorkspaceRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type MemberRequest struct {
+	Email string `json:"email" binding:"required,email,max=100"`
+	Role  string `json:"role" binding:"required,oneof=admin member guest"`
+}
+
+type MemberRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin member guest"`
+}
+
+// WorkspaceResponse is a workspace with the role the user holds in it.
+type WorkspaceResponse struct {
+	models.Workspace
+	Role string `json:"role"`
+}
+
+// taskSpace is where a request's tasks and projects live: the user's own,
+// or the active workspace's, which are held by the workspace owner.
+type taskSpace struct {
+	OwnerID     uint
+	WorkspaceID *uint
+}
+
+// activeSpace returns the space of the workspace AuthMiddleware selected,
+// or the user's personal space when none was.
+func activeSpace(c *gin.Context) (taskSpace, error) {
+	workspaceID := c.GetUint("workspaceID")
+	if workspaceID == 0 {
+		return taskSpace{OwnerID: c.GetUint("userID")}, nil
+	}
+
+	var workspace models.Workspace
+	if err := database.DB.First(&workspace, workspaceID).Error; err != nil {
+		return taskSpace{}, err
+	}
+	return taskSpace{OwnerID: workspace.OwnerID, WorkspaceID: &workspace.ID}, nil
+}
+
+// spaceOf returns the space task or project lives in.
+func spaceOf(ownerID uint, workspaceID *uint) taskSpace {
+	return taskSpace{OwnerID: ownerID, WorkspaceID: workspaceID}
+}
+
+// scope limits a task or project query to the space. The personal space
+// leaves out the user's workspace tasks, which they may hold as owner.
+func (s taskSpace) scope(db *gorm.DB) *gorm.DB {
+	if s.WorkspaceID != nil {
+		return db.Where("workspace_id = ?", *s.WorkspaceID)
+	}
+	return db.Where("user_id = ? AND workspace_id IS NULL", s.OwnerID)
+}
+
+// place puts a new task in the space.
+func (s taskSpace) place(task *models.Task) {
+	task.UserID = s.OwnerID
+	task.WorkspaceID = s.WorkspaceID
+}
+
+// users returns everyone working in the space.
+func (s taskSpace) users() []uint {
+	if s.WorkspaceID == nil {
+		return []uint{s.OwnerID}
+	}
+	return workspaceMembers(*s.WorkspaceID)
+}
+
+// notify sends the "update" refresh signal to everyone working in the
+// space.
+func (s taskSpace) notify() {
+	for _, userID := range s.users() {
+		notifyTaskUpdate(userID)
+	}
+}
+
+// canChangeProject reports whether userID, who may edit task, may also
+// move it between projects. Its owner can, and so can anyone editing a
+// workspace task, as the workspace's projects belong to the whole team.
+func canChangeProject(userID uint, task models.Task) bool {
+	return task.UserID == userID || task.WorkspaceID != nil
+}
+
+// requireWorkspaceRole checks the user holds at least role in the active
+// workspace, responding 403 if not. In their personal space a user may do
+// anything.
+func requireWorkspaceRole(c *gin.Context, role string) bool {
+	if c.GetUint("workspaceID") == 0 {
+		return true
+	}
+	if workspaceRoleLevels[c.GetString("workspaceRole")] >= workspaceRoleLevels[role] {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": errNotPermitted.Error()})
+	return false
+}
+
+// workspaceRole returns userID's role in a workspace, or "" if they aren't
+// a member.
+func workspaceRole(userID, workspaceID uint) (string, error) {
+	var roles []string
+	if err := database.DB.Model(&models.Membership{}).
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		Pluck("role", &roles).Error; err != nil {
+		return "", err
+	}
+	if len(roles) == 0 {
+		return "", nil
+	}
+	return roles[0], nil
+}
+
+func workspaceMembers(workspaceID uint) []uint {
+	var userIDs []uint
+	database.DB.Model(&models.Membership{}).Where("workspace_id = ?", workspaceID).Pluck("user_id", &userIDs)
+	return userIDs
+}
+
+// findWorkspaceFor loads a workspace userID holds at least role in, with
+// the role they hold. Workspaces they aren't a member of are reported as
+// not found.
+func findWorkspaceFor(userID uint, workspaceID string, role string) (models.Workspace, string, error) {
+	var workspace models.Workspace
+	if err := database.DB.Where("id = ?", workspaceID).First(&workspace).Error; err != nil {
+		return workspace, "", err
+	}
+
+	held, err := workspaceRole(userID, workspace.ID)
+	if err != nil {
+		return workspace, "", err
+	}
+	if held == "" {
+		return workspace, "", gorm.ErrRecordNotFound
+	}
+	if workspaceRoleLevels[held] < workspaceRoleLevels[role] {
+		return workspace, held, errNotPermitted
+	}
+	return workspace, held, nil
+}
+
+func GetWorkspaces(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var memberships []models.Membership
+	if err := database.DB.Where("user_id = ?", userID).Find(&memberships).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	roles := make(map[uint]string, len(memberships))
+	workspaceIDs := make([]uint, 0, len(memberships))
+	for _, membership := range memberships {
+		roles[membership.WorkspaceID] = membership.Role
+		workspaceIDs = append(workspaceIDs, membership.WorkspaceID)
+	}
+
+	workspaces := []WorkspaceResponse{}
+	if len(workspaceIDs) > 0 {
+		var found []models.Workspace
+		if err := database.DB.Where("id IN (?)", workspaceIDs).Order("name asc").Find(&found).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, workspace := range found {
+			workspaces = append(workspaces, WorkspaceResponse{Workspace: workspace, Role: roles[workspace.ID]})
+		}
+	}
+
+	c.JSON(http.StatusOK, workspaces)
+}
+
+// CreateWorkspace creates a workspace owned by the user. Its tasks will use
+// the user's statuses and priorities.
+func CreateWorkspace(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var workspaceReq WorkspaceRequest
+	if err := c.ShouldBindJSON(&workspaceReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workspace := models.Workspace{Name: workspaceReq.Name, OwnerID: userID}
+
+	tx := database.DB.Begin()
+
+	if err := tx.Create(&workspace).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	membership := models.Membership{WorkspaceID: workspace.ID, UserID: userID, Role: models.WorkspaceOwner}
+	if err := tx.Create(&membership).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, WorkspaceResponse{Workspace: workspace, Role: models.WorkspaceOwner})
+}
+
+func GetWorkspace(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	workspace, role, err := findWorkspaceFor(userID, c.Param("id"), models.WorkspaceGuest)
+	if err != nil {
+		respondAccessError(c, err, "Workspace not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, WorkspaceResponse{Workspace: workspace, Role: role})
+}
+
+func UpdateWorkspace(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	workspace, role, err := findWorkspaceFor(userID, c.Param("id"), models.WorkspaceAdmin)
+	if err != nil {
+		respondAccessError(c, err, "Workspace not found")
+		return
+	}
+
+	var workspaceReq WorkspaceRequest
+	if err := c.ShouldBindJSON(&workspaceReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workspace.Name = workspaceReq.Name
+	if err := database.DB.Save(&workspace).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	spaceOf(workspace.OwnerID, &workspace.ID).notify()
+	c.JSON(http.StatusOK, WorkspaceResponse{Workspace: workspace, Role: role})
+}
+
+// DeleteWorkspace removes a workspace and its memberships. Only the owner
+// can. Its tasks and projects are kept and become the owner's own, as they
+// were already held under the owner's account.
+func DeleteWorkspace(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	workspace, _, err := findWorkspaceFor(userID, c.Param("id"), models.WorkspaceOwner)
+	if err != nil {
+		respondAccessError(c, err, "Workspace not found")
+		return
+	}
+
+	members := workspaceMembers(workspace.ID)
+
+	tx := database.DB.Begin()
+
+	for _, model := range []interface{}{&models.Task{}, &models.Project{}} {
+		if err := tx.Unscoped().Model(model).Where("workspace_id = ?", workspace.ID).UpdateColumn("workspace_id", nil).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Where("workspace_id = ?", workspace.ID).Delete(&models.Membership{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Delete(&workspace).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, id := range members {
+		notifyTaskUpdate(id)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Workspace deleted successfully"})
+}
+
+func GetMembers(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	workspace, _, err := findWorkspaceFor(userID, c.Param("id"), models.WorkspaceGuest)
+	if err != nil {
+		respondAccessError(c, err, "Workspace not found")
+		return
+	}
+
+	var memberships []models.Membership
+	if err := database.DB.Where("workspace_id = ?", workspace.ID).Preload("User").Order("created_at asc").Find(&memberships).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, memberships)
+}
+
+// AddMember adds a registered user to the workspace by email. Admins can
+// add members and guests; only the owner can make someone an admin.
+func AddMember(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	workspace, role, err := findWorkspaceFor(userID, c.Param("id"), models.WorkspaceAdmin)
+	if err != nil {
+		respondAccessError(c, err, "Workspace not found")
+		return
+	}
+
+	var memberReq MemberRequest
+	if err := c.ShouldBindJSON(&memberReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if memberReq.Role == models.WorkspaceAdmin && role != models.WorkspaceOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can add admins"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(memberReq.Email))).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No user with that email"})
+		return
+	}
+
+	existing, err := workspaceRole(user.ID, workspace.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if existing != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member"})
+		return
+	}
+
+	membership := models.Membership{WorkspaceID: workspace.ID, UserID: user.ID, User: user.Summary(), Role: memberReq.Role}
+	if err := database.DB.Create(&membership).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyTaskEvent(user.ID, TaskEvent{Type: "workspace.joined", Data: workspace})
+	c.JSON(http.StatusCreated, membership)
+}
+
+// findMembership loads a membership of workspace for changing or removing
+// it. Admins manage members and guests; only the owner manages admins,
+// and the owner's own membership can't be touched.
+func findMembership(workspace models.Workspace, role string, membershipID string) (models.Membership, error) {
+	var membership models.Membership
+	if err := database.DB.Where("id = ? AND workspace_id = ?", membershipID, workspace.ID).Preload("User").First(&membership).Error; err != nil {
+		return membership, err
+	}
+	if membership.Role == models.WorkspaceOwner {
+		return membership, errOwnerMembership
+	}
+	if membership.Role == models.WorkspaceAdmin && role != models.WorkspaceOwner {
+		return membership, errNotPermitted
+	}
+	return membership, nil
+}
+
+func respondMembershipError(c *gin.Context, err error) {
+	if errors.Is(err, errOwnerMembership) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	respondAccessError(c, err, "Member not found")
+}
+
+func UpdateMember(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	workspace, role, err := findWorkspaceFor(userID, c.Param("id"), models.WorkspaceAdmin)
+	if err != nil {
+		respondAccessError(c, err, "Workspace not found")
+		return
+	}
+
+	membership, err := findMembership(workspace, role, c.Param("member_id"))
+	if err != nil {
+		respondMembershipError(c, err)
+		return
+	}
+
+	var roleReq MemberRoleRequest
+	if err := c.ShouldBindJSON(&roleReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if roleReq.Role == models.WorkspaceAdmin && role != models.WorkspaceOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can make someone an admin"})
+		return
+	}
+
+	membership.Role = roleReq.Role
+	if err := database.DB.Save(&membership).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyTaskUpdate(membership.UserID)
+	c.JSON(http.StatusOK, membership)
+}
+
+// RemoveMember takes a user out of the workspace. Besides admins removing
+// others, any member other than the owner can remove themselves to leave.
+func RemoveMember(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	workspace, role, err := findWorkspaceFor(userID, c.Param("id"), models.WorkspaceGuest)
+	if err != nil {
+		respondAccessError(c, err, "Workspace not found")
+		return
+	}
+
+	var membership models.Membership
+	if err := database.DB.Where("id = ? AND workspace_id = ? AND user_id = ?", c.Param("member_id"), workspace.ID, userID).First(&membership).Error; err != nil {
+		// Not leaving, so removing someone else.
+		if workspaceRoleLevels[role] < workspaceRoleLevels[models.WorkspaceAdmin] {
+			respondAccessError(c, errNotPermitted, "")
+			return
+		}
+		if membership, err = findMembership(workspace, role, c.Param("member_id")); err != nil {
+			respondMembershipError(c, err)
+			return
+		}
+	} else if membership.Role == models.WorkspaceOwner {
+		respondMembershipError(c, errOwnerMembership)
+		return
+	}
+
+	if err := database.DB.Delete(&membership).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	notifyTaskEvent(membership.UserID, TaskEvent{Type: "workspace.left", Data: gin.H{"workspace_id": workspace.ID}})
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
@@ -0,0 +1,137 @@
+// Package ical writes iCalendar (RFC 5545) data.
+//
+// It covers what the task feeds need: calendars holding VTODO and VEVENT
+// components, with text escaping and line folding done for the caller.
+package ical
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405Z"
+
+	// maxLineOctets is the longest a content line may be before folding.
+	maxLineOctets = 75
+)
+
+// Property is one content line, e.g. DUE;VALUE=DATE:20250512. Value is
+// written as given; use Text for free-form text values.
+type Property struct {
+	Name   string
+	Params []string
+	Value  string
+}
+
+// Component is a calendar component such as VTODO or VEVENT, along with any
+// components nested inside it.
+type Component struct {
+	Name       string
+	Properties []Property
+	Components []Component
+}
+
+// Add appends a property to the component.
+func (c *Component) Add(name, value string, params ...string) {
+	c.Properties = append(c.Properties, Property{Name: name, Params: params, Value: value})
+}
+
+// Calendar is a VCALENDAR object.
+type Calendar struct {
+	ProdID     string
+	Properties []Property
+	Components []Component
+}
+
+// Text escapes a TEXT value (RFC 5545 section 3.3.11).
+func Text(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// TextList escapes and joins values for a multi-valued property such as
+// CATEGORIES.
+func TextList(values []string) string {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = Text(v)
+	}
+	return strings.Join(escaped, ",")
+}
+
+// Date formats a DATE value for t's calendar day in its own location.
+func Date(t time.Time) string {
+	return t.Format(dateLayout)
+}
+
+// DateTime formats a DATE-TIME value in UTC.
+func DateTime(t time.Time) string {
+	return t.UTC().Format(dateTimeLayout)
+}
+
+// WriteTo writes the calendar with CRLF line endings and folded lines.
+func (cal *Calendar) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	writeLine(&buf, Property{Name: "BEGIN", Value: "VCALENDAR"})
+	writeLine(&buf, Property{Name: "VERSION", Value: "2.0"})
+	writeLine(&buf, Property{Name: "PRODID", Value: cal.ProdID})
+	for _, p := range cal.Properties {
+		writeLine(&buf, p)
+	}
+	for _, component := range cal.Components {
+		component.write(&buf)
+	}
+	writeLine(&buf, Property{Name: "END", Value: "VCALENDAR"})
+	return buf.WriteTo(w)
+}
+
+// Bytes returns the component serialised on its own, as it appears inside
+// a calendar.
+func (c Component) Bytes() []byte {
+	var buf bytes.Buffer
+	c.write(&buf)
+	return buf.Bytes()
+}
+
+func (c Component) write(buf *bytes.Buffer) {
+	writeLine(buf, Property{Name: "BEGIN", Value: c.Name})
+	for _, p := range c.Properties {
+		writeLine(buf, p)
+	}
+	for _, child := range c.Components {
+		child.write(buf)
+	}
+	writeLine(buf, Property{Name: "END", Value: c.Name})
+}
+
+// writeLine writes p as a content line, folding it so that no physical
+// line exceeds 75 octets and no UTF-8 sequence is split.
+func writeLine(buf *bytes.Buffer, p Property) {
+	line := p.Name
+	for _, param := range p.Params {
+		line += ";" + param
+	}
+	line += ":" + p.Value
+
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > maxLineOctets {
+			buf.WriteString("\r\n ")
+			width = 1
+		}
+		buf.WriteRune(r)
+		width += size
+	}
+	buf.WriteString("\r\n")
+}
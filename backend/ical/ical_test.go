@@ -0,0 +1,125 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestText(t *testing.T) {
+	tests := map[string]string{
+		"plain":              "plain",
+		`a\b`:                `a\\b`,
+		"one; two, three":    `one\; two\, three`,
+		"line\nbreak":        `line\nbreak`,
+		"crlf\r\nand\rcr":    `crlf\nand\ncr`,
+		`already \n escaped`: `already \\n escaped`,
+	}
+	for in, want := range tests {
+		if got := Text(in); got != want {
+			t.Errorf("Text(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	if got, want := TextList([]string{"Work", "a,b", "c;d"}), `Work,a\,b,c\;d`; got != want {
+		t.Errorf("TextList = %q, want %q", got, want)
+	}
+}
+
+func TestDateFormats(t *testing.T) {
+	loc := time.FixedZone("UTC+10", 10*3600)
+	at := time.Date(2025, 5, 12, 8, 30, 0, 0, loc)
+
+	if got := Date(at); got != "20250512" {
+		t.Errorf("Date = %q, want the day in its own location", got)
+	}
+	if got := DateTime(at); got != "20250511T223000Z" {
+		t.Errorf("DateTime = %q, want UTC", got)
+	}
+}
+
+func TestWriteLineFolding(t *testing.T) {
+	tests := []string{
+		"short",
+		strings.Repeat("x", 200),
+		strings.Repeat("é", 100),
+		strings.Repeat("ab✓", 60),
+		strings.Repeat("🙂", 40),
+	}
+	for _, value := range tests {
+		var buf bytes.Buffer
+		writeLine(&buf, Property{Name: "SUMMARY", Params: []string{"LANGUAGE=en"}, Value: value})
+		out := buf.String()
+
+		if !strings.HasSuffix(out, "\r\n") {
+			t.Fatalf("line for %q doesn't end in CRLF", value)
+		}
+		lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+		for i, line := range lines {
+			if len(line) > maxLineOctets {
+				t.Errorf("physical line %d is %d octets", i, len(line))
+			}
+			if !utf8.ValidString(line) {
+				t.Errorf("physical line %d splits a UTF-8 sequence", i)
+			}
+			if i > 0 && !strings.HasPrefix(line, " ") {
+				t.Errorf("continuation line %d doesn't start with a space", i)
+			}
+		}
+
+		unfolded, err := unfold(strings.NewReader(out))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "SUMMARY;LANGUAGE=en:" + value; len(unfolded) != 1 || unfolded[0] != want {
+			t.Errorf("unfolded %q, want %q", unfolded, want)
+		}
+	}
+}
+
+func TestCalendarWriteTo(t *testing.T) {
+	todo := Component{Name: "VTODO"}
+	todo.Add("UID", "task-1@taskmanager")
+	todo.Add("DUE", "20250512", "VALUE=DATE")
+	todo.Add("SUMMARY", Text("Pay rent, on time"))
+	todo.Components = []Component{{
+		Name:       "VALARM",
+		Properties: []Property{{Name: "ACTION", Value: "DISPLAY"}},
+	}}
+
+	cal := Calendar{
+		ProdID:     "-//Test//EN",
+		Properties: []Property{{Name: "CALSCALE", Value: "GREGORIAN"}},
+		Components: []Component{todo},
+	}
+	var buf bytes.Buffer
+	if _, err := cal.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Test//EN",
+		"CALSCALE:GREGORIAN",
+		"BEGIN:VTODO",
+		"UID:task-1@taskmanager",
+		"DUE;VALUE=DATE:20250512",
+		`SUMMARY:Pay rent\, on time`,
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"END:VALARM",
+		"END:VTODO",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	if got := buf.String(); got != want {
+		t.Errorf("WriteTo wrote\n%q\nwant\n%q", got, want)
+	}
+
+	if got := string(todo.Bytes()); !strings.HasPrefix(got, "BEGIN:VTODO\r\n") || !strings.HasSuffix(got, "END:VTODO\r\n") {
+		t.Errorf("Bytes = %q", got)
+	}
+}
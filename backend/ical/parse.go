@@ -0,0 +1,228 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"taskmanager/dates"
+)
+
+var ErrMalformed = errors.New("malformed iCalendar data")
+
+// Parse reads one VCALENDAR object, unfolding lines and keeping nested
+// components (such as a VALARM inside a VTODO) under their parent.
+func Parse(r io.Reader) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var stack []*Component
+	var cal *Calendar
+	for _, line := range lines {
+		p, err := parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		switch strings.ToUpper(p.Name) {
+		case "BEGIN":
+			name := strings.ToUpper(p.Value)
+			if cal == nil {
+				if name != "VCALENDAR" {
+					return nil, fmt.Errorf("%w: expected BEGIN:VCALENDAR", ErrMalformed)
+				}
+				cal = &Calendar{}
+				stack = append(stack, &Component{Name: name})
+				continue
+			}
+			stack = append(stack, &Component{Name: name})
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(p.Value) {
+				return nil, fmt.Errorf("%w: unexpected END:%s", ErrMalformed, p.Value)
+			}
+			done := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				for _, prop := range done.Properties {
+					switch prop.Name {
+					case "PRODID":
+						cal.ProdID = prop.Value
+					case "VERSION":
+					default:
+						cal.Properties = append(cal.Properties, prop)
+					}
+				}
+				cal.Components = done.Components
+				return cal, nil
+			}
+			parent := stack[len(stack)-1]
+			parent.Components = append(parent.Components, *done)
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("%w: property outside VCALENDAR", ErrMalformed)
+			}
+			top := stack[len(stack)-1]
+			top.Properties = append(top.Properties, p)
+		}
+	}
+	return nil, fmt.Errorf("%w: missing END:VCALENDAR", ErrMalformed)
+}
+
+// unfold joins folded continuation lines and drops blank ones.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseLine splits a content line into name, parameters and value. Colons
+// and semicolons inside quoted parameter values don't count as separators.
+func parseLine(line string) (Property, error) {
+	var p Property
+	inQuotes := false
+	start := 0
+	for i, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+		case r == ';' || r == ':':
+			part := line[start:i]
+			if p.Name == "" {
+				p.Name = strings.ToUpper(part)
+			} else {
+				p.Params = append(p.Params, part)
+			}
+			start = i + 1
+			if r == ':' {
+				p.Value = line[start:]
+				if p.Name == "" {
+					return p, fmt.Errorf("%w: line without a name", ErrMalformed)
+				}
+				return p, nil
+			}
+		}
+	}
+	return p, fmt.Errorf("%w: line without a value: %q", ErrMalformed, line)
+}
+
+// Get returns the component's first property called name.
+func (c Component) Get(name string) (Property, bool) {
+	for _, p := range c.Properties {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Property{}, false
+}
+
+// All returns every property of the component called name.
+func (c Component) All(name string) []Property {
+	var found []Property
+	for _, p := range c.Properties {
+		if p.Name == name {
+			found = append(found, p)
+		}
+	}
+	return found
+}
+
+// Param returns the value of the named parameter, without quotes.
+func (p Property) Param(name string) string {
+	for _, param := range p.Params {
+		key, value, ok := strings.Cut(param, "=")
+		if ok && strings.EqualFold(key, name) {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
+
+// Unescape reverses Text.
+func Unescape(s string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		if escaped {
+			switch r {
+			case 'n', 'N':
+				b.WriteRune('\n')
+			default:
+				b.WriteRune(r)
+			}
+			escaped = false
+			continue
+		}
+		if r == '\\' {
+			escaped = true
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// SplitList splits a multi-valued TEXT property on unescaped commas and
+// unescapes each value.
+func SplitList(s string) []string {
+	var values []string
+	start := 0
+	escaped := false
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ',':
+			values = append(values, Unescape(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(values, Unescape(s[start:]))
+}
+
+// ParseTime reads a DATE or DATE-TIME property. Dates, floating times and
+// times in an unknown TZID are read in loc; UTC times and times with a
+// known TZID are exact. It reports whether the value was a date.
+func ParseTime(p Property, loc *time.Location) (time.Time, bool, error) {
+	value := strings.TrimSpace(p.Value)
+	if strings.EqualFold(p.Param("VALUE"), "DATE") || len(value) == len(dateLayout) {
+		t, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return time.Time{}, true, err
+		}
+		return dates.Day(t.Year(), t.Month(), t.Day(), loc), true, nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(dateTimeLayout, value)
+		return t, false, err
+	}
+	if tzid := p.Param("TZID"); tzid != "" {
+		if tz, err := time.LoadLocation(tzid); err == nil {
+			loc = tz
+		}
+	}
+	t, err := time.Parse("20060102T150405", value)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return dates.WallClock(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), loc), false, nil
+}
@@ -0,0 +1,166 @@
+package ical
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Client//EN",
+		"X-WR-CALNAME:Tasks",
+		"BEGIN:VTODO",
+		"UID:abc-123",
+		"SUMMARY:A long summary that a client folded",
+		"  across two lines",
+		`DESCRIPTION:Line one\nLine two\, with a comma`,
+		`X-APPLE-STRUCTURED-LOCATION;VALUE=URI;X-TITLE="Office: 3rd floor; east":geo:1,2`,
+		"",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"END:VALARM",
+		"END:VTODO",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+
+	cal, err := Parse(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cal.ProdID != "-//Client//EN" {
+		t.Errorf("ProdID = %q", cal.ProdID)
+	}
+	if len(cal.Properties) != 1 || cal.Properties[0].Name != "X-WR-CALNAME" {
+		t.Errorf("Properties = %+v", cal.Properties)
+	}
+	if len(cal.Components) != 1 {
+		t.Fatalf("got %d components, want 1", len(cal.Components))
+	}
+
+	todo := cal.Components[0]
+	if todo.Name != "VTODO" {
+		t.Errorf("Name = %q", todo.Name)
+	}
+	if p, _ := todo.Get("SUMMARY"); p.Value != "A long summary that a client folded across two lines" {
+		t.Errorf("SUMMARY = %q", p.Value)
+	}
+	if p, _ := todo.Get("DESCRIPTION"); Unescape(p.Value) != "Line one\nLine two, with a comma" {
+		t.Errorf("DESCRIPTION = %q", Unescape(p.Value))
+	}
+	loc, ok := todo.Get("X-APPLE-STRUCTURED-LOCATION")
+	if !ok || loc.Value != "geo:1,2" || loc.Param("x-title") != "Office: 3rd floor; east" {
+		t.Errorf("quoted parameter parsed as %+v", loc)
+	}
+	if _, ok := todo.Get("LOCATION"); ok {
+		t.Error("Get found a missing property")
+	}
+	if len(todo.Components) != 1 || todo.Components[0].Name != "VALARM" {
+		t.Errorf("nested components = %+v", todo.Components)
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := []string{
+		"",
+		"BEGIN:VTODO\r\nEND:VTODO\r\n",
+		"SUMMARY:outside\r\nBEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n",
+		"BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+		"BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nEND:VTODO\r\n",
+		"BEGIN:VCALENDAR\r\nno colon here\r\nEND:VCALENDAR\r\n",
+		"BEGIN:VCALENDAR\r\n:no name\r\nEND:VCALENDAR\r\n",
+	}
+	for _, data := range tests {
+		if _, err := Parse(strings.NewReader(data)); !errors.Is(err, ErrMalformed) {
+			t.Errorf("Parse(%q) error = %v, want ErrMalformed", data, err)
+		}
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	todo := Component{Name: "VTODO"}
+	todo.Add("SUMMARY", Text(strings.Repeat("Wrap ✓ this; please, ", 10)))
+	todo.Add("CATEGORIES", TextList([]string{"Home", "a,b"}))
+	cal := Calendar{ProdID: "-//Test//EN", Components: []Component{todo}}
+
+	var b strings.Builder
+	cal.WriteTo(&b)
+	parsed, err := Parse(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed.Components, cal.Components) {
+		t.Errorf("round trip gave %+v, want %+v", parsed.Components, cal.Components)
+	}
+	summary, _ := parsed.Components[0].Get("SUMMARY")
+	if got := Unescape(summary.Value); got != strings.Repeat("Wrap ✓ this; please, ", 10) {
+		t.Errorf("SUMMARY = %q", got)
+	}
+	categories, _ := parsed.Components[0].Get("CATEGORIES")
+	if got := SplitList(categories.Value); !reflect.DeepEqual(got, []string{"Home", "a,b"}) {
+		t.Errorf("CATEGORIES = %q", got)
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := map[string][]string{
+		"one":            {"one"},
+		`a,b\,c,d`:       {"a", "b,c", "d"},
+		`x\\,y`:          {`x\`, "y"},
+		"":               {""},
+		`trailing,`:      {"trailing", ""},
+		`esc\;aped\nnew`: {"esc;aped\nnew"},
+	}
+	for in, want := range tests {
+		if got := SplitList(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("SplitList(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("timezone data unavailable:", err)
+	}
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone data unavailable:", err)
+	}
+
+	tests := []struct {
+		p      Property
+		want   time.Time
+		isDate bool
+	}{
+		{Property{Name: "DUE", Params: []string{"VALUE=DATE"}, Value: "20250512"}, time.Date(2025, 5, 12, 0, 0, 0, 0, loc), true},
+		{Property{Name: "DUE", Value: "20250512"}, time.Date(2025, 5, 12, 0, 0, 0, 0, loc), true},
+		{Property{Name: "DUE", Value: "20250512T153000Z"}, time.Date(2025, 5, 12, 15, 30, 0, 0, time.UTC), false},
+		{Property{Name: "DUE", Value: "20250512T153000"}, time.Date(2025, 5, 12, 15, 30, 0, 0, loc), false},
+		{Property{Name: "DUE", Params: []string{"TZID=America/New_York"}, Value: "20250512T153000"}, time.Date(2025, 5, 12, 15, 30, 0, 0, ny), false},
+		{Property{Name: "DUE", Params: []string{"TZID=Custom/Zone"}, Value: "20250512T153000"}, time.Date(2025, 5, 12, 15, 30, 0, 0, loc), false},
+		// 02:30 on 30 March 2025 doesn't exist in Berlin.
+		{Property{Name: "DUE", Value: "20250330T023000"}, time.Date(2025, 3, 30, 3, 30, 0, 0, loc), false},
+	}
+	for _, tt := range tests {
+		got, isDate, err := ParseTime(tt.p, loc)
+		if err != nil {
+			t.Errorf("ParseTime(%+v): %v", tt.p, err)
+			continue
+		}
+		if !got.Equal(tt.want) || isDate != tt.isDate {
+			t.Errorf("ParseTime(%+v) = %v, %v, want %v, %v", tt.p, got, isDate, tt.want, tt.isDate)
+		}
+	}
+
+	for _, value := range []string{"2025-05-12", "20251312", "20250512T25000Z", "soon"} {
+		if _, _, err := ParseTime(Property{Name: "DUE", Value: value}, loc); err == nil {
+			t.Errorf("ParseTime(%q) accepted an invalid value", value)
+		}
+	}
+}
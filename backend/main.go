@@ -3,7 +3,10 @@ package main
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -11,7 +14,7 @@ import (
 	"taskmanager/database"
 	"taskmanager/handlers"
 	"taskmanager/middleware"
-	"taskmanager/models"
+	"taskmanager/storage"
 )
 
 func main() {
@@ -33,17 +36,30 @@ func main() {
 	database.InitDBWithConfig(dbConfig)
 	defer database.CloseDB()
 
-	database.DB.AutoMigrate(&models.User{}, &models.Task{})
+	storage.InitStorage()
 
-	r := gin.Default()
+	if err := database.Migrate(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	trashRetention := handlers.DefaultTrashRetention
+	if days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS")); err == nil && days > 0 {
+		trashRetention = time.Duration(days) * 24 * time.Hour
+	}
+	handlers.ScheduleTrashPurge(trashRetention, time.Hour)
+
+	// Feed URLs carry their credential, so the access log redacts it.
+	r := gin.New()
+	r.Use(middleware.Logger(), gin.Recovery())
 
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Workspace-ID")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
-		if c.Request.Method == "OPTIONS" {
+		// CalDAV clients rely on OPTIONS to discover the server's capabilities.
+		if c.Request.Method == "OPTIONS" && !strings.HasPrefix(c.Request.URL.Path, "/caldav") {
 			c.AbortWithStatus(204)
 			return
 		}
@@ -54,13 +70,136 @@ func main() {
 	r.POST("/register", handlers.Register)
 	r.POST("/login", handlers.Login)
 
+	feeds := r.Group("/feeds/:token")
+	feeds.Use(middleware.FeedTokenMiddleware())
+	{
+		feeds.GET("/tasks.ics", handlers.GetTaskFeed)
+	}
+
+	r.GET("/.well-known/caldav", handlers.WellKnownCalDAV)
+	r.Handle("PROPFIND", "/.well-known/caldav", handlers.WellKnownCalDAV)
+
+	caldav := r.Group("/caldav")
+	caldav.Use(middleware.BasicAuthMiddleware("Tasks"))
+	{
+		for _, method := range []string{"OPTIONS", "PROPFIND", "REPORT", "GET", "HEAD", "PUT", "DELETE"} {
+			caldav.Handle(method, "/*path", handlers.CalDAV)
+		}
+	}
+
 	protected := r.Group("/")
 	protected.Use(middleware.AuthMiddleware())
 	{
+		protected.GET("/me", handlers.GetCurrentUser)
+		protected.PUT("/me/settings", handlers.UpdateUserSettings)
+		protected.POST("/me/feed-token", handlers.CreateFeedToken)
+		protected.DELETE("/me/feed-token", handlers.RevokeFeedToken)
+		protected.POST("/me/app-password", handlers.CreateAppPassword)
+		protected.DELETE("/me/app-password", handlers.RevokeAppPassword)
+
 		protected.GET("/tasks", handlers.GetTasks)
 		protected.POST("/tasks", handlers.CreateTask)
+		protected.POST("/tasks/quick", handlers.QuickAddTask)
+		protected.POST("/tasks/bulk", handlers.BulkTasks)
+		protected.GET("/tasks/export", handlers.ExportTasks)
+		protected.GET("/tasks/assigned", handlers.GetAssignedTasks)
+		protected.POST("/tasks/import", handlers.ImportTasks)
 		protected.PUT("/tasks/:id", handlers.UpdateTask)
+		protected.PATCH("/tasks/:id", handlers.PatchTask)
 		protected.DELETE("/tasks/:id", handlers.DeleteTask)
+		protected.GET("/tasks/trash", handlers.GetTrash)
+		protected.POST("/tasks/:id/restore", handlers.RestoreTask)
+		protected.DELETE("/tasks/:id/purge", handlers.PurgeTask)
+		protected.PUT("/tasks/:id/project", handlers.MoveTaskToProject)
+		protected.POST("/tasks/:id/move", handlers.MoveTask)
+		protected.POST("/tasks/:id/assignees", handlers.AddAssignee)
+		protected.DELETE("/tasks/:id/assignees/:user_id", handlers.RemoveAssignee)
+		protected.GET("/tasks/:id/status-history", handlers.GetTaskStatusHistory)
+		protected.GET("/tasks/:id/history", handlers.GetTaskHistory)
+		protected.POST("/tasks/:id/revert/:revision", handlers.RevertTask)
+		protected.GET("/workflow/transitions", handlers.GetStatusTransitions)
+
+		protected.GET("/tasks/:id/comments", handlers.GetComments)
+		protected.POST("/tasks/:id/comments", handlers.CreateComment)
+		protected.PUT("/tasks/:id/comments/:comment_id", handlers.UpdateComment)
+		protected.DELETE("/tasks/:id/comments/:comment_id", handlers.DeleteComment)
+		protected.GET("/tasks/:id/comments/:comment_id/history", handlers.GetCommentHistory)
+
+		protected.GET("/tasks/:id/attachments", handlers.GetAttachments)
+		protected.POST("/tasks/:id/attachments", handlers.UploadAttachment)
+		protected.GET("/tasks/:id/attachments/:attachment_id", handlers.DownloadAttachment)
+		protected.DELETE("/tasks/:id/attachments/:attachment_id", handlers.DeleteAttachment)
+
+		protected.GET("/projects", handlers.GetProjects)
+		protected.POST("/projects", handlers.CreateProject)
+		protected.PUT("/projects/:id", handlers.UpdateProject)
+		protected.DELETE("/projects/:id", handlers.DeleteProject)
+		protected.GET("/projects/:id/tasks", handlers.GetProjectTasks)
+		protected.GET("/projects/:id/counts", handlers.GetProjectTaskCounts)
+
+		protected.GET("/tasks/:id/shares", handlers.GetTaskShares)
+		protected.POST("/tasks/:id/shares", handlers.CreateTaskShare)
+		protected.GET("/projects/:id/shares", handlers.GetProjectShares)
+		protected.POST("/projects/:id/shares", handlers.CreateProjectShare)
+		protected.PUT("/shares/:id", handlers.UpdateShare)
+		protected.DELETE("/shares/:id", handlers.DeleteShare)
+		protected.GET("/shared", handlers.GetSharedWithMe)
+		protected.GET("/invitations", handlers.GetInvitations)
+		protected.POST("/invitations/:id/accept", handlers.AcceptInvitation)
+		protected.POST("/invitations/:id/decline", handlers.DeclineInvitation)
+
+		protected.GET("/notifications", handlers.GetNotifications)
+		protected.POST("/notifications/read-all", handlers.MarkAllNotificationsRead)
+		protected.POST("/notifications/:id/read", handlers.MarkNotificationRead)
+
+		protected.GET("/workspaces", handlers.GetWorkspaces)
+		protected.POST("/workspaces", handlers.CreateWorkspace)
+		protected.GET("/workspaces/:id", handlers.GetWorkspace)
+		protected.PUT("/workspaces/:id", handlers.UpdateWorkspace)
+		protected.DELETE("/workspaces/:id", handlers.DeleteWorkspace)
+		protected.GET("/workspaces/:id/members", handlers.GetMembers)
+		protected.POST("/workspaces/:id/members", handlers.AddMember)
+		protected.PUT("/workspaces/:id/members/:member_id", handlers.UpdateMember)
+		protected.DELETE("/workspaces/:id/members/:member_id", handlers.RemoveMember)
+
+		protected.GET("/views", handlers.GetViews)
+		protected.POST("/views", handlers.CreateView)
+		protected.GET("/views/:id", handlers.GetView)
+		protected.PUT("/views/:id", handlers.UpdateView)
+		protected.DELETE("/views/:id", handlers.DeleteView)
+		protected.GET("/views/:id/tasks", handlers.GetViewTasks)
+
+		protected.GET("/templates", handlers.GetTemplates)
+		protected.POST("/templates", handlers.CreateTemplate)
+		protected.GET("/templates/:id", handlers.GetTemplate)
+		protected.PUT("/templates/:id", handlers.UpdateTemplate)
+		protected.DELETE("/templates/:id", handlers.DeleteTemplate)
+		protected.POST("/templates/:id/instantiate", handlers.InstantiateTemplate)
+
+		protected.GET("/tags", handlers.GetTags)
+
+		protected.GET("/statuses", handlers.GetStatuses)
+		protected.POST("/statuses", handlers.CreateStatus)
+		protected.PUT("/statuses/:id", handlers.UpdateStatus)
+		protected.DELETE("/statuses/:id", handlers.DeleteStatus)
+
+		protected.GET("/priorities", handlers.GetPriorities)
+		protected.POST("/priorities", handlers.CreatePriority)
+		protected.PUT("/priorities/:id", handlers.UpdatePriority)
+		protected.DELETE("/priorities/:id", handlers.DeletePriority)
+
+		protected.GET("/tasks/:id/time-entries", handlers.GetTaskTimeEntries)
+		protected.POST("/tasks/:id/time-entries", handlers.CreateTimeEntry)
+		protected.PUT("/time-entries/:id", handlers.UpdateTimeEntry)
+		protected.DELETE("/time-entries/:id", handlers.DeleteTimeEntry)
+		protected.GET("/time-entries/totals", handlers.GetTimeTotals)
+
+		protected.GET("/effort", handlers.GetEffortSummary)
+		protected.GET("/stats", handlers.GetStats)
+
+		protected.GET("/timer", handlers.GetRunningTimer)
+		protected.POST("/tasks/:id/timer/start", handlers.StartTimer)
+		protected.POST("/timer/stop", handlers.StopTimer)
 
 		protected.GET("/ws", handlers.TaskWebSocket)
 	}
@@ -0,0 +1,44 @@
+// Package mentions finds @mentions such as "@alex" or "@alex.kim" in task
+// descriptions and comments. A mention starts with "@" at the beginning of
+// the text or after a character that can't be part of a word, so email
+// addresses aren't read as mentions. Handles are letters, digits and
+// ". _ -", and trailing dots or dashes are taken as punctuation.
+package mentions
+
+import (
+	"regexp"
+	"strings"
+)
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@])@([\p{L}\p{N}][\p{L}\p{N}._-]*)`)
+
+// Parse returns the handles mentioned in text, lower-cased and without the
+// "@", each once, in the order they first appear.
+func Parse(text string) []string {
+	var handles []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		handle := strings.ToLower(strings.TrimRight(match[1], ".-"))
+		if handle != "" && !seen[handle] {
+			seen[handle] = true
+			handles = append(handles, handle)
+		}
+	}
+	return handles
+}
+
+// EmailHandle returns the handle a user is mentioned by through their
+// email address: its local part, lower-cased.
+func EmailHandle(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return ""
+	}
+	return strings.ToLower(email[:at])
+}
+
+// NameHandle returns the handle a user is mentioned by through their name:
+// the name without spaces, lower-cased.
+func NameHandle(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), ""))
+}
@@ -0,0 +1,73 @@
+package mentions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"no mentions here", nil},
+		{"@alex can you look?", []string{"alex"}},
+		{"Ask @Alex.Kim and @sam_o about it", []string{"alex.kim", "sam_o"}},
+		{"Thanks @alex.", []string{"alex"}},
+		{"cc @jo-, @lee--", []string{"jo", "lee"}},
+		{"(@alex) [@sam]", []string{"alex", "sam"}},
+		{"@alex @ALEX @Alex", []string{"alex"}},
+		{"mail alex@example.com", nil},
+		{"a@b and x.@y and _@z", nil},
+		{"@@alex", nil},
+		{"@ alex and @.alex and @-sam", nil},
+		{"Frag @jürgen oder @李", []string{"jürgen", "李"}},
+		{"line one\n@sam on line two", []string{"sam"}},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEmailHandle(t *testing.T) {
+	tests := map[string]string{
+		"Alex.Kim@example.com":  "alex.kim",
+		"sam@mail.example.org":  "sam",
+		"odd\"@name\"@host.com": "odd\"@name\"",
+		"@example.com":          "",
+		"no-at-sign":            "",
+		"":                      "",
+	}
+	for email, want := range tests {
+		if got := EmailHandle(email); got != want {
+			t.Errorf("EmailHandle(%q) = %q, want %q", email, got, want)
+		}
+	}
+}
+
+func TestNameHandle(t *testing.T) {
+	tests := map[string]string{
+		"Alex Kim":          "alexkim",
+		"  Mary  Ann\tLee ": "maryannlee",
+		"Jürgen":            "jürgen",
+		"":                  "",
+	}
+	for name, want := range tests {
+		if got := NameHandle(name); got != want {
+			t.Errorf("NameHandle(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// TestHandlesMatchParse checks that what a user's email and name give as
+// handles is what Parse reads back from a mention of them.
+func TestHandlesMatchParse(t *testing.T) {
+	for _, handle := range []string{EmailHandle("alex.kim@example.com"), EmailHandle("sam_o@example.com"), NameHandle("Mary Ann")} {
+		if got := Parse("hi @" + handle + "!"); !reflect.DeepEqual(got, []string{handle}) {
+			t.Errorf("Parse of @%s = %q", handle, got)
+		}
+	}
+}
@@ -2,11 +2,15 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
+
+	"taskmanager/database"
+	"taskmanager/models"
 )
 
 var jwtSecret = []byte("your-secret-key") 
@@ -51,6 +55,28 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		c.Set("userID", claims.UserID)
+
+		// X-Workspace-ID picks the workspace the request acts in, and the
+		// user's role there comes along with it. Without the header the
+		// user is in their personal space and workspaceID is 0.
+		if header := c.GetHeader("X-Workspace-ID"); header != "" {
+			workspaceID, err := strconv.ParseUint(header, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid X-Workspace-ID"})
+				c.Abort()
+				return
+			}
+
+			var membership models.Membership
+			if err := database.DB.Where("workspace_id = ? AND user_id = ?", workspaceID, claims.UserID).First(&membership).Error; err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this workspace"})
+				c.Abort()
+				return
+			}
+			c.Set("workspaceID", membership.WorkspaceID)
+			c.Set("workspaceRole", membership.Role)
+		}
+
 		c.Next()
 	}
 }
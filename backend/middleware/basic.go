@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/database"
+	"taskmanager/models"
+)
+
+// GenerateAppPassword returns a new random CalDAV app password and the hash
+// to store for it. Like feed tokens, only the hash is kept.
+func GenerateAppPassword() (password, hash string, err error) {
+	return generateSecret()
+}
+
+// BasicAuthMiddleware authenticates clients such as CalDAV apps that only
+// support HTTP Basic authentication, using the account's email and its app
+// password. The account's own password is not accepted: it would cost a
+// bcrypt comparison on every request and can't be revoked on its own.
+// OPTIONS requests pass through so clients can discover the server's
+// capabilities before logging in.
+func BasicAuthMiddleware(realm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		email, password, ok := c.Request.BasicAuth()
+		if ok {
+			var user models.User
+			if err := database.DB.Where("LOWER(email) = LOWER(?)", email).First(&user).Error; err == nil && user.AppPasswordHash != nil &&
+				subtle.ConstantTimeCompare([]byte(hashSecret(password)), []byte(*user.AppPasswordHash)) == 1 {
+				c.Set("userID", user.ID)
+				c.Next()
+				return
+			}
+		}
+
+		c.Header("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+		c.AbortWithStatus(http.StatusUnauthorized)
+	}
+}
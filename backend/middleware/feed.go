@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"taskmanager/database"
+	"taskmanager/models"
+)
+
+// GenerateFeedToken returns a new random calendar feed token and the hash
+// to store for it. Only the hash is kept, so a token can't be recovered
+// from the database, only replaced.
+func GenerateFeedToken() (token, hash string, err error) {
+	return generateSecret()
+}
+
+func HashFeedToken(token string) string {
+	return hashSecret(token)
+}
+
+// generateSecret returns a random URL-safe secret and its hash. Secrets
+// are long enough that a fast hash is as good as a password hash.
+func generateSecret() (secret, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	secret = base64.RawURLEncoding.EncodeToString(raw)
+	return secret, hashSecret(secret), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// FeedTokenMiddleware authenticates calendar clients, which can't send an
+// Authorization header, by the feed token in the URL.
+func FeedTokenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Param("token")
+		if token == "" {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		var user models.User
+		if err := database.DB.Where("feed_token_hash = ?", HashFeedToken(token)).First(&user).Error; err != nil {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		c.Set("userID", user.ID)
+		c.Next()
+	}
+}
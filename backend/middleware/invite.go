@@ -0,0 +1,11 @@
+package middleware
+
+// GenerateInviteToken returns a new random share invitation token and the
+// hash to store for it. Like feed tokens, only the hash is kept.
+func GenerateInviteToken() (token, hash string, err error) {
+	return generateSecret()
+}
+
+func HashInviteToken(token string) string {
+	return hashSecret(token)
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const redacted = "REDACTED"
+
+// Logger is gin's request logger with credentials taken out of the logged
+// path: the token of /feeds/:token URLs and any token query parameter.
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		var statusColor, methodColor, resetColor string
+		if param.IsOutputColor() {
+			statusColor = param.StatusCodeColor()
+			methodColor = param.MethodColor()
+			resetColor = param.ResetColor()
+		}
+
+		if param.Latency > time.Minute {
+			param.Latency = param.Latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			statusColor, param.StatusCode, resetColor,
+			param.Latency,
+			param.ClientIP,
+			methodColor, param.Method, resetColor,
+			RedactPath(param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
+
+// RedactPath replaces the credentials in a request path and query string.
+func RedactPath(path string) string {
+	path, query, hasQuery := strings.Cut(path, "?")
+
+	if rest, ok := strings.CutPrefix(path, "/feeds/"); ok {
+		path = "/feeds/" + redacted
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			path += rest[i:]
+		}
+	}
+
+	if !hasQuery {
+		return path
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		// An unparseable query can't be redacted selectively.
+		return path + "?" + redacted
+	}
+	if values.Has("token") {
+		values.Set("token", redacted)
+		query = values.Encode()
+	}
+	return path + "?" + query
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import "testing"
+
+func TestRedactPath(t *testing.T) {
+	tests := map[string]string{
+		"/feeds/s3cr3t/tasks.ics":         "/feeds/REDACTED/tasks.ics",
+		"/feeds/s3cr3t":                   "/feeds/REDACTED",
+		"/feeds/s3cr3t/tasks.ics?days=30": "/feeds/REDACTED/tasks.ics?days=30",
+		"/ws?token=eyJhbGciOi":            "/ws?token=REDACTED",
+		"/tasks?status=Pending&token=abc": "/tasks?status=Pending&token=REDACTED",
+		"/tasks?status=Pending":           "/tasks?status=Pending",
+		"/tasks?bad=%zz":                  "/tasks?REDACTED",
+		"/tasks/12":                       "/tasks/12",
+		"/api/feeds/not-a-feed-token":     "/api/feeds/not-a-feed-token",
+	}
+	for path, want := range tests {
+		if got := RedactPath(path); got != want {
+			t.Errorf("RedactPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
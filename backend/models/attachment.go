@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Attachment links an uploaded file to a task. The file contents live in
+// blob storage under Checksum, so identical uploads share a single blob.
+type Attachment struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	TaskID    uint      `json:"task_id" gorm:"not null;index"`
+	UserID    uint      `json:"user_id" gorm:"not null"`
+	FileName  string    `json:"file_name" gorm:"not null"`
+	MimeType  string    `json:"mime_type" gorm:"type:varchar(255);not null"`
+	Size      int64     `json:"size" gorm:"not null"`
+	Checksum  string    `json:"checksum" gorm:"type:char(64);not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// CalendarObject remembers the resource name and UID a CalDAV client gave a
+// task it created, so the client finds the task under the same name and UID
+// on its next sync. Tasks created elsewhere have no row and are served under
+// a name and UID derived from their ID.
+type CalendarObject struct {
+	ID     uint   `json:"id" gorm:"primary_key"`
+	UserID uint   `json:"user_id" gorm:"not null;unique_index:idx_calendar_objects_user_name"`
+	TaskID uint   `json:"task_id" gorm:"not null;unique_index"`
+	Name   string `json:"name" gorm:"type:varchar(255);not null;unique_index:idx_calendar_objects_user_name"`
+	UID    string `json:"uid" gorm:"type:varchar(255);not null"`
+}
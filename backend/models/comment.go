@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Comment is a markdown note attached to a task. Body holds the raw
+// markdown; rendering is left to the client.
+type Comment struct {
+	gorm.Model
+	TaskID   uint       `json:"task_id" gorm:"not null;index"`
+	Task     Task       `json:"-" gorm:"foreignkey:TaskID"`
+	UserID   uint       `json:"user_id" gorm:"not null"`
+	User     User       `json:"-" gorm:"foreignkey:UserID"`
+	Body     string     `json:"body" gorm:"type:text;not null"`
+	EditedAt *time.Time `json:"edited_at"`
+}
+
+// CommentRevision keeps a previous body of a comment each time it is edited.
+type CommentRevision struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	CommentID uint      `json:"comment_id" gorm:"not null;index"`
+	Body      string    `json:"body" gorm:"type:text;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
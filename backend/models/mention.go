@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Mention records that a task's description, or one of its comments when
+// CommentID is set, mentions a user.
+type Mention struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	TaskID    uint      `json:"task_id" gorm:"not null;index"`
+	CommentID *uint     `json:"comment_id" gorm:"index"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	AuthorID  uint      `json:"author_id" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NotificationMention is the type of a notification telling a user they
+// were mentioned.
+const NotificationMention = "mention"
+
+// Notification is an entry in a user's inbox. It stays there, read or
+// not, after the WebSocket message announcing it has gone.
+type Notification struct {
+	ID        uint        `json:"id" gorm:"primary_key"`
+	UserID    uint        `json:"user_id" gorm:"not null;index"`
+	Type      string      `json:"type" gorm:"type:varchar(30);not null"`
+	ActorID   uint        `json:"actor_id" gorm:"not null"`
+	Actor     UserSummary `json:"actor" gorm:"foreignkey:ActorID;association_autoupdate:false;association_autocreate:false"`
+	TaskID    uint        `json:"task_id" gorm:"not null;index"`
+	CommentID *uint       `json:"comment_id"`
+	Excerpt   string      `json:"excerpt" gorm:"type:text"`
+	ReadAt    *time.Time  `json:"read_at"`
+	CreatedAt time.Time   `json:"created_at"`
+}
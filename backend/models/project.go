@@ -0,0 +1,17 @@
+package models
+
+import "github.com/jinzhu/gorm"
+
+// Project groups a user's tasks into a board. Tasks without a project
+// remain in the user's unsorted list. A workspace's projects are held by
+// its owner, like its tasks.
+type Project struct {
+	gorm.Model
+	UserID   uint   `json:"user_id" gorm:"not null;index"`
+	User     User   `json:"-" gorm:"foreignkey:UserID"`
+	Name     string `json:"name" gorm:"type:varchar(100);not null"`
+	Color    string `json:"color" gorm:"type:varchar(7)"`
+	Archived bool   `json:"archived" gorm:"not null;default:false"`
+
+	WorkspaceID *uint `json:"workspace_id" gorm:"index"`
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+)
+
+// TaskSnapshot holds the user-editable fields of a task at one point in time.
+type TaskSnapshot struct {
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	Status        string     `json:"status"`
+	Priority      string     `json:"priority"`
+	DueDate       *time.Time `json:"due_date"`
+	DueAllDay     bool       `json:"due_all_day"`
+	ProjectID     *uint      `json:"project_id"`
+	EstimateHours *float64   `json:"estimate_hours"`
+	StoryPoints   *int       `json:"story_points"`
+}
+
+// Snapshot captures the task's current editable state.
+func (t Task) Snapshot() TaskSnapshot {
+	return TaskSnapshot{
+		Title:         t.Title,
+		Description:   t.Description,
+		Status:        t.Status,
+		Priority:      t.Priority,
+		DueDate:       t.DueDate,
+		DueAllDay:     t.DueAllDay,
+		ProjectID:     t.ProjectID,
+		EstimateHours: t.EstimateHours,
+		StoryPoints:   t.StoryPoints,
+	}
+}
+
+// fields lists the snapshot's values by their JSON name, with pointers
+// dereferenced and times normalised so that equal values compare equal.
+func (s TaskSnapshot) fields() []FieldChange {
+	var dueDate interface{}
+	if s.DueDate != nil {
+		dueDate = s.DueDate.UTC().Format(time.RFC3339)
+	}
+	return []FieldChange{
+		{Field: "title", After: s.Title},
+		{Field: "description", After: s.Description},
+		{Field: "status", After: s.Status},
+		{Field: "priority", After: s.Priority},
+		{Field: "due_date", After: dueDate},
+		{Field: "due_all_day", After: s.DueAllDay},
+		{Field: "project_id", After: deref(s.ProjectID)},
+		{Field: "estimate_hours", After: deref(s.EstimateHours)},
+		{Field: "story_points", After: deref(s.StoryPoints)},
+	}
+}
+
+func deref(ptr interface{}) interface{} {
+	v := reflect.ValueOf(ptr)
+	if v.IsNil() {
+		return nil
+	}
+	return v.Elem().Interface()
+}
+
+// Diff returns the fields that differ between s and next, in a fixed order.
+func (s TaskSnapshot) Diff(next TaskSnapshot) FieldChanges {
+	before, after := s.fields(), next.fields()
+	changes := FieldChanges{}
+	for i := range before {
+		if !reflect.DeepEqual(before[i].After, after[i].After) {
+			changes = append(changes, FieldChange{
+				Field:  before[i].Field,
+				Before: before[i].After,
+				After:  after[i].After,
+			})
+		}
+	}
+	return changes
+}
+
+func (s TaskSnapshot) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+func (s *TaskSnapshot) Scan(src interface{}) error {
+	if err := scanJSON(src, s); err != nil {
+		return err
+	}
+	// Snapshots taken before due dates were optional stored "no due date"
+	// as the zero time.
+	if s.DueDate != nil && s.DueDate.IsZero() {
+		s.DueDate = nil
+	}
+	return nil
+}
+
+// FieldChange is the before and after value of one field in a revision.
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type FieldChanges []FieldChange
+
+func (c FieldChanges) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *FieldChanges) Scan(src interface{}) error {
+	return scanJSON(src, c)
+}
+
+func scanJSON(src interface{}, dest interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	case nil:
+		return nil
+	}
+	return errors.New("unsupported JSON column value")
+}
+
+// TaskRevision records one change to a task: who made it, which fields
+// changed, and the task's state afterwards. A revision created by reverting
+// to an earlier one points at it through RevertedFrom; one recording that
+// the task came back from the trash has Restored set and no changes.
+type TaskRevision struct {
+	ID           uint         `json:"id" gorm:"primary_key"`
+	TaskID       uint         `json:"task_id" gorm:"not null;index"`
+	UserID       uint         `json:"user_id" gorm:"not null"`
+	Changes      FieldChanges `json:"changes" gorm:"type:jsonb;not null"`
+	Snapshot     TaskSnapshot `json:"snapshot" gorm:"type:jsonb;not null"`
+	RevertedFrom *uint        `json:"reverted_from"`
+	Restored     bool         `json:"restored" gorm:"not null;default:false"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Roles a share can grant. Viewers can read a task and its comments,
+// attachments and history; editors can also change it. Only the owner can
+// delete a task, move it between projects or manage who it is shared with.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+)
+
+// Share gives another user access to one of the owner's tasks, or to a
+// project and every task in it. It is created as an invitation to an email
+// address and takes effect once accepted. An invitation to a registered
+// user is bound to them by UserID straight away. One to an address nobody
+// has registered yet carries an invite token the owner passes on, which
+// must be presented to accept it, so registering with the address alone
+// grants nothing; UserID is set on acceptance.
+type Share struct {
+	ID         uint       `json:"id" gorm:"primary_key"`
+	OwnerID    uint       `json:"owner_id" gorm:"not null;index"`
+	Owner      User       `json:"-" gorm:"foreignkey:OwnerID"`
+	TaskID     *uint      `json:"task_id" gorm:"index"`
+	ProjectID  *uint      `json:"project_id" gorm:"index"`
+	Email      string     `json:"email" gorm:"type:varchar(100);not null;index"`
+	UserID     *uint      `json:"user_id" gorm:"index"`
+	Role       string     `json:"role" gorm:"type:varchar(10);not null"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+
+	// InviteTokenHash is the hash of the invite token of an invitation
+	// that isn't bound to a user.
+	InviteTokenHash *string `json:"-" gorm:"type:char(64)"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Accepted reports whether the invitation has been accepted, so the share
+// grants access.
+func (s *Share) Accepted() bool {
+	return s.AcceptedAt != nil
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Status categories group user-defined statuses by where they sit in a
+// task's lifecycle.
+const (
+	CategoryTodo       = "todo"
+	CategoryInProgress = "in_progress"
+	CategoryDone       = "done"
+)
+
+// TaskStatus is a status a user can put their tasks in. Tasks refer to it
+// by Name; Category decides how the status workflow treats it.
+type TaskStatus struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	UserID    uint      `json:"user_id" gorm:"not null;unique_index:idx_task_statuses_user_name"`
+	Name      string    `json:"name" gorm:"type:varchar(50);not null;unique_index:idx_task_statuses_user_name"`
+	Color     string    `json:"color" gorm:"type:varchar(7)"`
+	Position  int       `json:"position" gorm:"not null;default:0"`
+	Category  string    `json:"category" gorm:"type:varchar(20);not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IsDone reports whether tasks in this status count as finished.
+func (s TaskStatus) IsDone() bool {
+	return s.Category == CategoryDone
+}
+
+// TaskPriority is a priority level a user can give their tasks. Tasks refer
+// to it by Name; a higher Position means a more urgent priority.
+type TaskPriority struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	UserID    uint      `json:"user_id" gorm:"not null;unique_index:idx_task_priorities_user_name"`
+	Name      string    `json:"name" gorm:"type:varchar(50);not null;unique_index:idx_task_priorities_user_name"`
+	Color     string    `json:"color" gorm:"type:varchar(7)"`
+	Position  int       `json:"position" gorm:"not null;default:0"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DefaultTaskStatuses are given to every user, matching the statuses that
+// existed before they became configurable.
+var DefaultTaskStatuses = []TaskStatus{
+	{Name: "Pending", Color: "#f59e0b", Position: 0, Category: CategoryTodo},
+	{Name: "In-Progress", Color: "#3b82f6", Position: 1, Category: CategoryInProgress},
+	{Name: "Completed", Color: "#10b981", Position: 2, Category: CategoryDone},
+}
+
+// DefaultTaskPriorities are given to every user, matching the priorities
+// that existed before they became configurable.
+var DefaultTaskPriorities = []TaskPriority{
+	{Name: "Low", Color: "#6b7280", Position: 0},
+	{Name: "Medium", Color: "#3b82f6", Position: 1},
+	{Name: "High", Color: "#f97316", Position: 2},
+	{Name: "Critical", Color: "#ef4444", Position: 3},
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Tag is a label a user can attach to any number of their tasks.
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	UserID    uint      `json:"-" gorm:"not null;unique_index:idx_tags_user_name"`
+	Name      string    `json:"name" gorm:"type:varchar(50);not null;unique_index:idx_tags_user_name"`
+	CreatedAt time.Time `json:"-"`
+}
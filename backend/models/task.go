@@ -8,11 +8,40 @@ import (
 
 type Task struct {
 	gorm.Model
-	UserID      uint      `json:"user_id" gorm:"not null"`
-	User        User      `json:"-" gorm:"foreignkey:UserID"`
-	Title       string    `json:"title" binding:"required"`
-	Description string    `json:"description"`
-	Status      string    `json:"status" binding:"required,oneof=Pending In-Progress Completed"`
-	Priority    string    `json:"priority" binding:"required,oneof=Low Medium High Critical"`
-	DueDate     time.Time `json:"due_date"`
+	UserID      uint       `json:"user_id" gorm:"not null"`
+	User        User       `json:"-" gorm:"foreignkey:UserID"`
+	Title       string     `json:"title" binding:"required"`
+	Description string     `json:"description"`
+	Status      string     `json:"status" binding:"required,max=50"`
+	Priority    string     `json:"priority" binding:"required,max=50"`
+	DueDate     *time.Time `json:"due_date"`
+	DueAllDay   bool       `json:"due_all_day" gorm:"not null;default:false"`
+	ProjectID   *uint      `json:"project_id" gorm:"index"`
+	WorkspaceID *uint      `json:"workspace_id" gorm:"index"`
+	Rank        string     `json:"rank" gorm:"type:varchar(64);index"`
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+
+	// EstimateHours and StoryPoints are optional sizing fields; teams may
+	// use either or both.
+	EstimateHours *float64 `json:"estimate_hours"`
+	StoryPoints   *int     `json:"story_points"`
+
+	Tags []Tag `json:"tags" gorm:"many2many:task_tags;association_autoupdate:false;association_autocreate:false"`
+
+	// Assignees are the users working on the task, separate from its owner.
+	// Users need access to the task to be assigned, but losing that access
+	// later doesn't unassign them.
+	Assignees []UserSummary `json:"assignees" gorm:"many2many:task_assignees;jointable_foreignkey:task_id;association_jointable_foreignkey:user_id;association_autoupdate:false;association_autocreate:false"`
+}
+
+// TaskStatusChange records one status change of a task: who made it and
+// when. The first entry of a task has an empty FromStatus.
+type TaskStatusChange struct {
+	ID         uint      `json:"id" gorm:"primary_key"`
+	TaskID     uint      `json:"task_id" gorm:"not null;index"`
+	UserID     uint      `json:"user_id" gorm:"not null"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
 }
@@ -0,0 +1,32 @@
+package models
+
+import "github.com/jinzhu/gorm"
+
+// TaskTemplate is a task a user creates over and over, such as an
+// onboarding checklist, kept so it can be instantiated in one step. Titles
+// and descriptions may contain {{variable}} placeholders that are filled in
+// when it is instantiated. An empty Priority means the user's default, and
+// DueOffsetDays places the due date relative to the instantiation date.
+type TaskTemplate struct {
+	gorm.Model
+	UserID        uint                  `json:"user_id" gorm:"not null;index"`
+	User          User                  `json:"-" gorm:"foreignkey:UserID"`
+	Name          string                `json:"name" gorm:"type:varchar(100);not null"`
+	Title         string                `json:"title" gorm:"not null"`
+	Description   string                `json:"description"`
+	Priority      string                `json:"priority" gorm:"type:varchar(50)"`
+	DueOffsetDays *int                  `json:"due_offset_days"`
+	Subtasks      []TaskTemplateSubtask `json:"subtasks" gorm:"foreignkey:TemplateID;association_autoupdate:false;association_autocreate:false"`
+}
+
+// TaskTemplateSubtask is one further task created along with its
+// template's main task, in Position order.
+type TaskTemplateSubtask struct {
+	ID            uint   `json:"id" gorm:"primary_key"`
+	TemplateID    uint   `json:"-" gorm:"not null;index"`
+	Position      int    `json:"position" gorm:"not null;default:0"`
+	Title         string `json:"title" gorm:"not null"`
+	Description   string `json:"description"`
+	Priority      string `json:"priority" gorm:"type:varchar(50)"`
+	DueOffsetDays *int   `json:"due_offset_days"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// TimeEntry is a span of time a user spent on a task. A running timer is an
+// entry with no StoppedAt; each user has at most one of those.
+type TimeEntry struct {
+	gorm.Model
+	TaskID          uint       `json:"task_id" gorm:"not null;index"`
+	Task            Task       `json:"-" gorm:"foreignkey:TaskID"`
+	UserID          uint       `json:"user_id" gorm:"not null;index"`
+	User            User       `json:"-" gorm:"foreignkey:UserID"`
+	StartedAt       time.Time  `json:"started_at" gorm:"not null"`
+	StoppedAt       *time.Time `json:"stopped_at"`
+	DurationSeconds int64      `json:"duration_seconds" gorm:"not null;default:0"`
+	Note            string     `json:"note" gorm:"type:text"`
+}
+
+// Running reports whether the entry is a timer that hasn't been stopped.
+func (e *TimeEntry) Running() bool {
+	return e.StoppedAt == nil
+}
+
+// Stop ends a running entry at the given time and records its duration.
+func (e *TimeEntry) Stop(at time.Time) {
+	e.StoppedAt = &at
+	e.DurationSeconds = int64(at.Sub(e.StartedAt) / time.Second)
+}
@@ -10,6 +10,22 @@ type User struct {
 	Email     string    `json:"email" gorm:"type:varchar(100);unique;not null"`
 	Password  string    `json:"-" gorm:"not null"`  
 	Name      string    `json:"name" gorm:"type:varchar(100)"`
+
+	// DailyCapacityHours is how many estimated hours of work the user can
+	// take on per day before due tasks are flagged as over capacity.
+	DailyCapacityHours float64 `json:"daily_capacity_hours" gorm:"not null;default:8"`
+
+	// Timezone is the IANA name all-day due dates are interpreted in.
+	Timezone string `json:"timezone" gorm:"type:varchar(64);not null;default:'UTC'"`
+
+	// FeedTokenHash identifies the user's calendar feed. It is NULL while
+	// no feed token has been issued.
+	FeedTokenHash *string `json:"-" gorm:"type:char(64);unique_index"`
+
+	// AppPasswordHash is the hash of the password CalDAV clients sign in
+	// with. It is NULL while none has been issued.
+	AppPasswordHash *string `json:"-" gorm:"type:char(64)"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -26,3 +42,21 @@ func (u *User) SetPassword(password string) error {
 func (u *User) ComparePassword(password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 }
+
+// UserSummary is how a user appears to other users: as a task's assignee,
+// a workspace member or the actor of a notification. It says who they are,
+// without their settings.
+type UserSummary struct {
+	ID    uint   `json:"id" gorm:"primary_key"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func (UserSummary) TableName() string {
+	return "users"
+}
+
+// Summary returns the part of u other users see.
+func (u User) Summary() UserSummary {
+	return UserSummary{ID: u.ID, Name: u.Name, Email: u.Email}
+}
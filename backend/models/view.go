@@ -0,0 +1,44 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// TaskFilter is the set of task list filters and the sort order GET /tasks
+// takes as query parameters. Empty fields don't filter.
+type TaskFilter struct {
+	Status     string `json:"status,omitempty"`
+	Priority   string `json:"priority,omitempty"`
+	DueDate    string `json:"due_date,omitempty"`
+	HasDueDate string `json:"has_due_date,omitempty"`
+	ProjectID  string `json:"project_id,omitempty"`
+	Tag        string `json:"tag,omitempty"`
+	Sort       string `json:"sort,omitempty"`
+
+	// Assignee is a user ID or "me", which means whoever runs the filter.
+	Assignee   string `json:"assignee,omitempty"`
+	Unassigned string `json:"unassigned,omitempty"`
+}
+
+func (f TaskFilter) Value() (driver.Value, error) {
+	return json.Marshal(f)
+}
+
+func (f *TaskFilter) Scan(src interface{}) error {
+	return scanJSON(src, f)
+}
+
+// SavedView is a named task filter a user can return to. At most one of a
+// user's views is their default, which is handed to the client at login.
+type SavedView struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	User      User       `json:"-" gorm:"foreignkey:UserID"`
+	Name      string     `json:"name" gorm:"type:varchar(100);not null"`
+	Filter    TaskFilter `json:"filter" gorm:"type:jsonb;not null"`
+	IsDefault bool       `json:"is_default" gorm:"not null;default:false"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Workspace roles, from most to least access. The owner created the
+// workspace and holds its tasks and projects; admins manage members and
+// can delete anything; members create and edit tasks; guests can only
+// read.
+const (
+	WorkspaceOwner  = "owner"
+	WorkspaceAdmin  = "admin"
+	WorkspaceMember = "member"
+	WorkspaceGuest  = "guest"
+)
+
+// Workspace is a team's shared space. Its tasks and projects are stored
+// under the owner's account with WorkspaceID set, so the whole team works
+// on one board with the owner's statuses and priorities.
+type Workspace struct {
+	gorm.Model
+	Name    string `json:"name" gorm:"type:varchar(100);not null"`
+	OwnerID uint   `json:"owner_id" gorm:"not null;index"`
+}
+
+// Membership gives a user a role in a workspace. Every workspace has
+// exactly one membership with the owner role, its creator's.
+type Membership struct {
+	ID          uint        `json:"id" gorm:"primary_key"`
+	WorkspaceID uint        `json:"workspace_id" gorm:"not null;unique_index:idx_memberships_workspace_user"`
+	UserID      uint        `json:"user_id" gorm:"not null;unique_index:idx_memberships_workspace_user;index"`
+	User        UserSummary `json:"user" gorm:"foreignkey:UserID;association_autoupdate:false;association_autocreate:false"`
+	Role        string      `json:"role" gorm:"type:varchar(10);not null"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
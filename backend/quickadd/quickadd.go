@@ -0,0 +1,322 @@
+// Package quickadd parses a one-line task description such as
+//
+//	Send invoice to Acme next Friday 3pm !high #billing
+//
+// into its title, due date, priority and tags. Words that aren't recognised
+// stay in the title, in their original order.
+//
+// Dates are understood as "today", "tonight", "tomorrow", weekday names
+// ("friday", "next friday"), "next week", "in 3 days" or "in 2 weeks",
+// month and day ("may 12", "12 may", "may 12th") and ISO dates
+// ("2025-05-12"), optionally preceded by "on", "by" or "due". Times are
+// "3pm", "3:30pm", "15:00", "noon" or "midnight", optionally preceded by
+// "at". A date alone is an all-day due date; a time alone is today, or
+// tomorrow if that time has already passed. Priorities are written "!name"
+// and tags "#name".
+package quickadd
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"taskmanager/dates"
+)
+
+// Result is the interpretation of a line. Matched lists the phrases that
+// were read as the due date, in the order they appeared.
+type Result struct {
+	Title     string     `json:"title"`
+	DueDate   *time.Time `json:"due_date"`
+	DueAllDay bool       `json:"due_all_day"`
+	Priority  string     `json:"priority,omitempty"`
+	Tags      []string   `json:"tags"`
+	Matched   []string   `json:"matched"`
+}
+
+// Weekdays are only recognised by their full names: abbreviations such as
+// "sat" and "sun" are ordinary words too often.
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+// datePrepositions may introduce a date and are dropped along with it.
+var datePrepositions = map[string]bool{"on": true, "by": true, "due": true}
+
+// Parse interprets line relative to now in loc.
+func Parse(line string, now time.Time, loc *time.Location) Result {
+	now = now.In(loc)
+	words := strings.Fields(line)
+	used := make([]bool, len(words))
+	result := Result{Tags: []string{}, Matched: []string{}}
+
+	var day *time.Time
+	var hour, minute int
+	hasTime := false
+
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+
+		if len(word) > 1 && word[0] == '!' && result.Priority == "" {
+			result.Priority = strings.TrimRight(word[1:], ".,;")
+			used[i] = true
+			continue
+		}
+		if len(word) > 1 && word[0] == '#' {
+			result.Tags = append(result.Tags, strings.TrimRight(word[1:], ".,;"))
+			used[i] = true
+			continue
+		}
+
+		if day == nil {
+			start := i
+			if datePrepositions[clean(word)] && i+1 < len(words) {
+				start = i + 1
+			}
+			if d, n, ok := parseDate(words[start:], now); ok {
+				day = &d
+				markUsed(used, i, start+n)
+				result.Matched = append(result.Matched, strings.Join(words[i:start+n], " "))
+				i = start + n - 1
+				continue
+			}
+		}
+
+		if !hasTime {
+			start := i
+			if clean(word) == "at" && i+1 < len(words) {
+				start = i + 1
+			}
+			if h, m, n, ok := parseTime(words[start:]); ok {
+				hour, minute, hasTime = h, m, true
+				markUsed(used, i, start+n)
+				result.Matched = append(result.Matched, strings.Join(words[i:start+n], " "))
+				i = start + n - 1
+				continue
+			}
+		}
+	}
+
+	switch {
+	case day != nil && hasTime:
+		due := dates.WallClock(day.Year(), day.Month(), day.Day(), hour, minute, 0, loc)
+		result.DueDate = &due
+	case day != nil:
+		result.DueDate = day
+		result.DueAllDay = true
+	case hasTime:
+		due := dates.WallClock(now.Year(), now.Month(), now.Day(), hour, minute, 0, loc)
+		if !due.After(now) {
+			due = dates.WallClock(now.Year(), now.Month(), now.Day()+1, hour, minute, 0, loc)
+		}
+		result.DueDate = &due
+	}
+
+	var title []string
+	for i, word := range words {
+		if !used[i] {
+			title = append(title, word)
+		}
+	}
+	result.Title = strings.Join(title, " ")
+	return result
+}
+
+func markUsed(used []bool, from, to int) {
+	for i := from; i < to; i++ {
+		used[i] = true
+	}
+}
+
+// clean lowercases a word and strips the punctuation that commonly follows
+// it in a sentence.
+func clean(word string) string {
+	return strings.ToLower(strings.TrimRight(word, ".,;"))
+}
+
+// parseDate reads a date from the start of words, returning the start of
+// that day and how many words it used.
+func parseDate(words []string, now time.Time) (time.Time, int, bool) {
+	if len(words) == 0 {
+		return time.Time{}, 0, false
+	}
+	today := dates.StartOfDay(now, now.Location())
+	first := clean(words[0])
+	second := ""
+	if len(words) > 1 {
+		second = clean(words[1])
+	}
+
+	switch first {
+	case "today", "tonight":
+		return today, 1, true
+	case "tomorrow", "tmr", "tmrw":
+		return dates.AddDays(today, 1), 1, true
+	case "next":
+		if weekday, ok := weekdays[second]; ok {
+			return nextWeekday(today, weekday), 2, true
+		}
+		if second == "week" {
+			return nextWeekday(today, time.Monday), 2, true
+		}
+	case "in":
+		if len(words) < 3 {
+			break
+		}
+		n, err := strconv.Atoi(second)
+		if err != nil || n < 0 {
+			break
+		}
+		switch strings.TrimSuffix(clean(words[2]), "s") {
+		case "day":
+			return dates.AddDays(today, n), 3, true
+		case "week":
+			return dates.AddDays(today, 7*n), 3, true
+		case "month":
+			return dates.Day(today.Year(), today.Month()+time.Month(n), today.Day(), today.Location()), 3, true
+		}
+	}
+
+	if weekday, ok := weekdays[first]; ok {
+		return nextWeekday(today, weekday), 1, true
+	}
+	if t, err := dates.ParseDay(first, now.Location()); err == nil {
+		return t, 1, true
+	}
+	if month, ok := months[first]; ok {
+		if d, ok := dayOfMonth(second); ok {
+			if t, ok := nextDate(today, month, d); ok {
+				return t, 2, true
+			}
+		}
+	}
+	if d, ok := dayOfMonth(first); ok {
+		if month, ok := months[second]; ok {
+			if t, ok := nextDate(today, month, d); ok {
+				return t, 2, true
+			}
+		}
+	}
+	return time.Time{}, 0, false
+}
+
+// nextWeekday returns the first given weekday after today.
+func nextWeekday(today time.Time, weekday time.Weekday) time.Time {
+	days := (int(weekday) - int(today.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return dates.AddDays(today, days)
+}
+
+// nextDate returns the first occurrence of month and day from today on:
+// this year, or next year if it has already passed. February 29 waits for
+// the next leap year, and days a month doesn't have aren't dates at all.
+func nextDate(today time.Time, month time.Month, day int) (time.Time, bool) {
+	// Leap years are at most eight years apart.
+	for year := today.Year(); year <= today.Year()+8; year++ {
+		t := dates.Day(year, month, day, today.Location())
+		if t.Day() == day && !t.Before(today) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// dayOfMonth reads "12", "12th", "1st", "2nd" or "3rd".
+func dayOfMonth(word string) (int, bool) {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		word = strings.TrimSuffix(word, suffix)
+	}
+	d, err := strconv.Atoi(word)
+	if err != nil || d < 1 || d > 31 {
+		return 0, false
+	}
+	return d, true
+}
+
+// parseTime reads a time of day from the start of words. Bare numbers are
+// not times, so "chapter 3" keeps its 3.
+func parseTime(words []string) (int, int, int, bool) {
+	if len(words) == 0 {
+		return 0, 0, 0, false
+	}
+	word := clean(words[0])
+	switch word {
+	case "noon", "midday":
+		return 12, 0, 1, true
+	case "midnight":
+		return 0, 0, 1, true
+	}
+
+	used := 1
+	suffix := ""
+	for _, s := range []string{"am", "pm", "a.m", "p.m"} {
+		if strings.HasSuffix(word, s) {
+			suffix = s[:1]
+			word = strings.TrimSuffix(word, s)
+			break
+		}
+	}
+	if suffix == "" && len(words) > 1 {
+		switch clean(words[1]) {
+		case "am", "a.m":
+			suffix, used = "a", 2
+		case "pm", "p.m":
+			suffix, used = "p", 2
+		}
+	}
+
+	hourText, minuteText, hasMinutes := strings.Cut(word, ":")
+	if suffix == "" && !hasMinutes {
+		return 0, 0, 0, false
+	}
+	hour, err := strconv.Atoi(hourText)
+	if err != nil || len(hourText) > 2 {
+		return 0, 0, 0, false
+	}
+	minute := 0
+	if hasMinutes {
+		minute, err = strconv.Atoi(minuteText)
+		if err != nil || len(minuteText) != 2 || minute > 59 {
+			return 0, 0, 0, false
+		}
+	}
+
+	switch suffix {
+	case "a", "p":
+		if hour < 1 || hour > 12 {
+			return 0, 0, 0, false
+		}
+		hour %= 12
+		if suffix == "p" {
+			hour += 12
+		}
+	default:
+		if hour > 23 {
+			return 0, 0, 0, false
+		}
+	}
+	return hour, minute, used, true
+}
@@ -0,0 +1,249 @@
+package quickadd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skip("timezone data unavailable:", err)
+	}
+	return loc
+}
+
+func TestParse(t *testing.T) {
+	loc := mustLoad(t, "America/New_York")
+	// Wednesday, 14 May 2025, mid-morning.
+	now := time.Date(2025, 5, 14, 10, 0, 0, 0, loc)
+	date := func(year int, month time.Month, day, hour, minute int) *time.Time {
+		d := time.Date(year, month, day, hour, minute, 0, 0, loc)
+		return &d
+	}
+
+	tests := []struct {
+		line     string
+		title    string
+		due      *time.Time
+		allDay   bool
+		priority string
+		tags     []string
+		matched  []string
+	}{
+		{
+			line:     "Send invoice to Acme next Friday 3pm !high #billing",
+			title:    "Send invoice to Acme",
+			due:      date(2025, 5, 16, 15, 0),
+			priority: "high",
+			tags:     []string{"billing"},
+			matched:  []string{"next Friday", "3pm"},
+		},
+		{
+			line:    "Renew passport in 2 weeks",
+			title:   "Renew passport",
+			due:     date(2025, 5, 28, 0, 0),
+			allDay:  true,
+			matched: []string{"in 2 weeks"},
+		},
+		{
+			line:    "Call mum in 1 day",
+			title:   "Call mum",
+			due:     date(2025, 5, 15, 0, 0),
+			allDay:  true,
+			matched: []string{"in 1 day"},
+		},
+		{
+			line:    "Dentist may 12th",
+			title:   "Dentist",
+			due:     date(2026, 5, 12, 0, 0),
+			allDay:  true,
+			matched: []string{"may 12th"},
+		},
+		{
+			line:    "Birthday party 20 May",
+			title:   "Birthday party",
+			due:     date(2025, 5, 20, 0, 0),
+			allDay:  true,
+			matched: []string{"20 May"},
+		},
+		{
+			line:    "Stand-up 9am",
+			title:   "Stand-up",
+			due:     date(2025, 5, 15, 9, 0),
+			matched: []string{"9am"},
+		},
+		{
+			line:    "Review PR 3:30 pm",
+			title:   "Review PR",
+			due:     date(2025, 5, 14, 15, 30),
+			matched: []string{"3:30 pm"},
+		},
+		{
+			line:    "Ship release on friday at 15:00",
+			title:   "Ship release",
+			due:     date(2025, 5, 16, 15, 0),
+			matched: []string{"on friday", "at 15:00"},
+		},
+		{
+			line:    "Lunch at noon tomorrow",
+			title:   "Lunch",
+			due:     date(2025, 5, 15, 12, 0),
+			matched: []string{"at noon", "tomorrow"},
+		},
+		{
+			line:    "File taxes by 2025-06-01",
+			title:   "File taxes",
+			due:     date(2025, 6, 1, 0, 0),
+			allDay:  true,
+			matched: []string{"by 2025-06-01"},
+		},
+		{
+			line:    "Plan sprint next week",
+			title:   "Plan sprint",
+			due:     date(2025, 5, 19, 0, 0),
+			allDay:  true,
+			matched: []string{"next week"},
+		},
+		{
+			line:    "Wednesday retro",
+			title:   "retro",
+			due:     date(2025, 5, 21, 0, 0),
+			allDay:  true,
+			matched: []string{"Wednesday"},
+		},
+		{
+			line:    "Read chapter 3",
+			title:   "Read chapter 3",
+			matched: []string{},
+		},
+		{
+			line:    "Meet at home and work on slides",
+			title:   "Meet at home and work on slides",
+			matched: []string{},
+		},
+		{
+			line:    "Pay rent feb 30",
+			title:   "Pay rent feb 30",
+			matched: []string{},
+		},
+		{
+			line:    "Leap day party feb 29",
+			title:   "Leap day party",
+			due:     date(2028, 2, 29, 0, 0),
+			allDay:  true,
+			matched: []string{"feb 29"},
+		},
+		{
+			line:     "Buy milk today, !low #home #errands.",
+			title:    "Buy milk",
+			due:      date(2025, 5, 14, 0, 0),
+			allDay:   true,
+			tags:     []string{"home", "errands"},
+			matched:  []string{"today,"},
+			priority: "low",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got := Parse(tt.line, now, loc)
+			if got.Title != tt.title {
+				t.Errorf("Title = %q, want %q", got.Title, tt.title)
+			}
+			switch {
+			case tt.due == nil && got.DueDate != nil:
+				t.Errorf("DueDate = %v, want none", got.DueDate)
+			case tt.due != nil && (got.DueDate == nil || !got.DueDate.Equal(*tt.due)):
+				t.Errorf("DueDate = %v, want %v", got.DueDate, tt.due)
+			}
+			if got.DueAllDay != tt.allDay {
+				t.Errorf("DueAllDay = %v, want %v", got.DueAllDay, tt.allDay)
+			}
+			if got.Priority != tt.priority {
+				t.Errorf("Priority = %q, want %q", got.Priority, tt.priority)
+			}
+			tags := tt.tags
+			if tags == nil {
+				tags = []string{}
+			}
+			if !reflect.DeepEqual(got.Tags, tags) {
+				t.Errorf("Tags = %q, want %q", got.Tags, tags)
+			}
+			if !reflect.DeepEqual(got.Matched, tt.matched) {
+				t.Errorf("Matched = %q, want %q", got.Matched, tt.matched)
+			}
+		})
+	}
+}
+
+func TestParseTimePassedToday(t *testing.T) {
+	loc := mustLoad(t, "Europe/Berlin")
+	now := time.Date(2025, 5, 14, 23, 30, 0, 0, loc)
+
+	got := Parse("Take out bins 11pm", now, loc)
+	want := time.Date(2025, 5, 15, 23, 0, 0, 0, loc)
+	if got.DueDate == nil || !got.DueDate.Equal(want) {
+		t.Errorf("DueDate = %v, want %v", got.DueDate, want)
+	}
+
+	got = Parse("Take out bins 11:45pm", now, loc)
+	want = time.Date(2025, 5, 14, 23, 45, 0, 0, loc)
+	if got.DueDate == nil || !got.DueDate.Equal(want) {
+		t.Errorf("DueDate = %v, want %v", got.DueDate, want)
+	}
+}
+
+func TestParseAcrossDST(t *testing.T) {
+	loc := mustLoad(t, "America/New_York")
+	// Clocks go forward from 02:00 to 03:00 on Sunday 9 March 2025.
+	now := time.Date(2025, 3, 8, 20, 0, 0, 0, loc)
+
+	got := Parse("Brunch tomorrow", now, loc)
+	want := time.Date(2025, 3, 9, 0, 0, 0, 0, loc)
+	if got.DueDate == nil || !got.DueDate.Equal(want) || !got.DueAllDay {
+		t.Errorf("tomorrow = %v, want all-day %v", got.DueDate, want)
+	}
+
+	got = Parse("Brunch tomorrow 11am", now, loc)
+	want = time.Date(2025, 3, 9, 11, 0, 0, 0, loc)
+	if got.DueDate == nil || !got.DueDate.Equal(want) {
+		t.Errorf("tomorrow 11am = %v, want %v", got.DueDate, want)
+	}
+	if _, offset := got.DueDate.Zone(); offset != -4*3600 {
+		t.Errorf("tomorrow 11am has offset %d, want EDT", offset)
+	}
+
+	// 02:30 doesn't exist that day; it lands on the same instant as 03:30.
+	got = Parse("Check backups tomorrow 2:30am", now, loc)
+	want = time.Date(2025, 3, 9, 3, 30, 0, 0, loc)
+	if got.DueDate == nil || !got.DueDate.Equal(want) {
+		t.Errorf("tomorrow 2:30am = %v, want %v", got.DueDate, want)
+	}
+
+	// A week after the change is still midnight local time, not 23:00.
+	got = Parse("Retro in 1 week", time.Date(2025, 3, 5, 9, 0, 0, 0, loc), loc)
+	want = time.Date(2025, 3, 12, 0, 0, 0, 0, loc)
+	if got.DueDate == nil || !got.DueDate.Equal(want) {
+		t.Errorf("in 1 week = %v, want %v", got.DueDate, want)
+	}
+
+	// On the day itself, a time already past rolls to the next day at the
+	// same wall-clock time.
+	now = time.Date(2025, 3, 9, 9, 0, 0, 0, loc)
+	got = Parse("Water plants 8am", now, loc)
+	want = time.Date(2025, 3, 10, 8, 0, 0, 0, loc)
+	if got.DueDate == nil || !got.DueDate.Equal(want) {
+		t.Errorf("8am = %v, want %v", got.DueDate, want)
+	}
+}
+
+func TestParseTimeRejectsNonTimes(t *testing.T) {
+	for _, word := range []string{"3", "13pm", "0am", "12:5", "24:00", "7:60", "123:00"} {
+		if _, _, _, ok := parseTime([]string{word}); ok {
+			t.Errorf("parseTime(%q) accepted a non-time", word)
+		}
+	}
+}
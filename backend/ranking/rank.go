@@ -0,0 +1,129 @@
+// Package ranking generates sortable string keys for manually ordered lists.
+//
+// Keys are base-36 fractions written with the digits 0-9a-z, so comparing
+// them as plain strings gives their order. A key can always be generated
+// between two existing keys, which lets a single row be moved without
+// renumbering its neighbours.
+package ranking
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+	base   = len(digits)
+
+	// width is the length of keys produced by Spread and Initial.
+	width = 4
+)
+
+// ErrNoSpace is returned by Between when no key exists between its
+// arguments, either because they are out of order or because they are
+// equal as fractions. Callers should rebalance the list with Spread.
+var ErrNoSpace = errors.New("no rank between the given keys")
+
+// Initial is the key given to the first item of an empty list. It sits in
+// the middle of the key space so items can be added above and below it.
+var Initial = digits[base/2:base/2+1] + strings.Repeat("0", width-1)
+
+// Between returns a key that sorts strictly after prev and strictly before
+// next. An empty prev means the start of the list and an empty next means
+// the end of the list.
+func Between(prev, next string) (string, error) {
+	if next != "" && prev >= next {
+		return "", ErrNoSpace
+	}
+
+	var out []byte
+	bounded := next != ""
+	for i := 0; ; i++ {
+		if bounded && i >= len(prev) && i >= len(next) {
+			return "", ErrNoSpace
+		}
+
+		lo := 0
+		if i < len(prev) {
+			lo = strings.IndexByte(digits, prev[i])
+		}
+		hi := base
+		if bounded {
+			hi = 0
+			if i < len(next) {
+				hi = strings.IndexByte(digits, next[i])
+			}
+		}
+		if lo < 0 || hi < 0 {
+			return "", ErrNoSpace
+		}
+
+		if hi-lo > 1 {
+			return string(append(out, digits[(lo+hi)/2])), nil
+		}
+
+		out = append(out, digits[lo])
+		if hi-lo == 1 {
+			// Anything starting with out is now below next, so only
+			// prev constrains the remaining digits.
+			bounded = false
+		}
+	}
+}
+
+// After returns a key that sorts after prev, used to append to the end of a
+// list. It increments prev in place where possible so that repeated appends
+// keep keys short.
+func After(prev string) string {
+	if prev == "" {
+		return Initial
+	}
+
+	key := []byte(prev)
+	for i := len(key) - 1; i >= 0; i-- {
+		d := strings.IndexByte(digits, key[i])
+		if d >= 0 && d < base-1 {
+			key[i] = digits[d+1]
+			return string(key)
+		}
+		key[i] = digits[0]
+	}
+
+	// Every digit was already the largest one; extend instead.
+	next, _ := Between(prev, "")
+	return next
+}
+
+// Spread returns n keys evenly distributed over the key space, in order.
+// It is used to assign ranks to an existing list from scratch.
+func Spread(n int) []string {
+	keys := make([]string, n)
+	if n == 0 {
+		return keys
+	}
+
+	w := width
+	space := 1
+	for i := 0; i < w; i++ {
+		space *= base
+	}
+	for space/(n+1) < base {
+		w++
+		space *= base
+	}
+
+	step := space / (n + 1)
+	for i := range keys {
+		keys[i] = encode((i+1)*step, w)
+	}
+	return keys
+}
+
+func encode(value, w int) string {
+	key := make([]byte, w)
+	for i := w - 1; i >= 0; i-- {
+		key[i] = digits[value%base]
+		value /= base
+	}
+	return string(key)
+}
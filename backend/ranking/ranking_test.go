@@ -0,0 +1,135 @@
+package ranking
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		prev, next string
+	}{
+		{"", ""},
+		{"", Initial},
+		{Initial, ""},
+		{"a", "b"},
+		{"a", "a1"},
+		{"az", "b"},
+		{"0", "01"},
+		{"zz", ""},
+		{"", "0001"},
+		{"h0", "h000001"},
+	}
+	for _, tt := range tests {
+		key, err := Between(tt.prev, tt.next)
+		if err != nil {
+			t.Errorf("Between(%q, %q): %v", tt.prev, tt.next, err)
+			continue
+		}
+		if key <= tt.prev || (tt.next != "" && key >= tt.next) {
+			t.Errorf("Between(%q, %q) = %q, not strictly between", tt.prev, tt.next, key)
+		}
+	}
+}
+
+func TestBetweenNoSpace(t *testing.T) {
+	tests := []struct {
+		prev, next string
+	}{
+		{"b", "a"},
+		{"a", "a"},
+		{"a", "a0"},
+		{"", "0"},
+		{"", "000"},
+		{"a", "A"},
+	}
+	for _, tt := range tests {
+		if key, err := Between(tt.prev, tt.next); !errors.Is(err, ErrNoSpace) {
+			t.Errorf("Between(%q, %q) = %q, %v, want ErrNoSpace", tt.prev, tt.next, key, err)
+		}
+	}
+}
+
+// TestRandomInserts keeps inserting between random neighbours, as dragging
+// cards around a board does, and checks the list stays strictly ordered.
+func TestRandomInserts(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	keys := []string{Initial}
+
+	for i := 0; i < 2000; i++ {
+		pos := rng.Intn(len(keys) + 1)
+		prev, next := "", ""
+		if pos > 0 {
+			prev = keys[pos-1]
+		}
+		if pos < len(keys) {
+			next = keys[pos]
+		}
+
+		key, err := Between(prev, next)
+		if err != nil {
+			t.Fatalf("insert %d: Between(%q, %q): %v", i, prev, next, err)
+		}
+		keys = append(keys[:pos], append([]string{key}, keys[pos:]...)...)
+	}
+
+	if !sort.StringsAreSorted(keys) {
+		t.Fatal("keys are not in order")
+	}
+	for i := 1; i < len(keys); i++ {
+		if keys[i-1] == keys[i] {
+			t.Fatalf("duplicate key %q", keys[i])
+		}
+	}
+}
+
+func TestAfter(t *testing.T) {
+	if got := After(""); got != Initial {
+		t.Errorf("After(\"\") = %q, want %q", got, Initial)
+	}
+
+	for _, prev := range []string{"0", "h000", "0z", "hzzz", "z", "zzzz"} {
+		if got := After(prev); got <= prev {
+			t.Errorf("After(%q) = %q, want a later key", prev, got)
+		}
+	}
+
+	key := Initial
+	for i := 0; i < 5000; i++ {
+		next := After(key)
+		if next <= key {
+			t.Fatalf("After(%q) = %q, want a later key", key, next)
+		}
+		key = next
+	}
+	if len(key) > width+1 {
+		t.Errorf("5000 appends grew keys to %q", key)
+	}
+}
+
+func TestSpread(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 10, 1000, 100000} {
+		keys := Spread(n)
+		if len(keys) != n {
+			t.Fatalf("Spread(%d) returned %d keys", n, len(keys))
+		}
+		for i, key := range keys {
+			if i > 0 && keys[i-1] >= key {
+				t.Fatalf("Spread(%d): %q is not after %q", n, key, keys[i-1])
+			}
+			if len(key) != len(keys[0]) {
+				t.Fatalf("Spread(%d): keys have different widths", n)
+			}
+		}
+		if n > 0 {
+			if _, err := Between("", keys[0]); err != nil {
+				t.Errorf("Spread(%d) left no room before the first key", n)
+			}
+			if _, err := Between(keys[n-1], ""); err != nil {
+				t.Errorf("Spread(%d) left no room after the last key", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// ErrNotFound is returned by Get when no blob is stored under the key.
+var ErrNotFound = errors.New("blob not found")
+
+// Storage persists opaque blobs addressed by key. Keys are chosen by the
+// caller and never contain path separators.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type Config struct {
+	Driver   string
+	LocalDir string
+	S3       S3Config
+}
+
+var Blobs Storage
+
+func DefaultConfig() Config {
+	return Config{
+		Driver:   getEnv("STORAGE_DRIVER", "local"),
+		LocalDir: getEnv("STORAGE_LOCAL_DIR", "uploads"),
+		S3: S3Config{
+			Endpoint:        getEnv("S3_ENDPOINT", "https://s3.amazonaws.com"),
+			Region:          getEnv("S3_REGION", "us-east-1"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PathStyle:       getEnv("S3_PATH_STYLE", "false") == "true",
+		},
+	}
+}
+
+func InitStorage() {
+	InitStorageWithConfig(DefaultConfig())
+}
+
+func InitStorageWithConfig(config Config) {
+	var err error
+	switch config.Driver {
+	case "local":
+		Blobs, err = NewLocalStorage(config.LocalDir)
+	case "s3":
+		Blobs, err = NewS3Storage(config.S3)
+	default:
+		err = fmt.Errorf("unknown storage driver %q", config.Driver)
+	}
+
+	if err != nil {
+		log.Fatalf("Failed to initialize %s storage: %v", config.Driver, err)
+	}
+	log.Printf("Attachment storage initialized using %s driver", config.Driver)
+}
+
+func validateKey(key string) error {
+	if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	return nil
+}
+
+func getEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,112 @@
+// Package workflow defines which status changes a task may go through and
+// keeps the task's lifecycle timestamps in step with its status.
+//
+// Statuses are user-defined, so the rules are written in terms of status
+// categories (todo, in progress, done) rather than status names.
+//
+// The model is deliberately permissive: every change between categories is
+// allowed, so boards, bulk edits and CalDAV clients can move a task anywhere.
+// What the package enforces is the timestamps, not the order of work.
+package workflow
+
+import (
+	"fmt"
+	"time"
+
+	"taskmanager/models"
+)
+
+// Guard can veto a transition for a particular task. It returns a non-nil
+// error describing why the change isn't allowed.
+type Guard func(task *models.Task) error
+
+// Transition is one allowed edge between status categories.
+type Transition struct {
+	From  string
+	To    string
+	Name  string
+	Guard Guard
+}
+
+// Transitions lists every allowed change between categories. Moving a task
+// between two statuses of the same category is always allowed. The list
+// covers every category pair and installs no guards; it exists to name the
+// edges and as the place to restrict one later, by removing it or giving it
+// a Guard.
+var Transitions = []Transition{
+	{From: models.CategoryTodo, To: models.CategoryInProgress, Name: "start"},
+	{From: models.CategoryTodo, To: models.CategoryDone, Name: "complete"},
+	{From: models.CategoryInProgress, To: models.CategoryDone, Name: "complete"},
+	{From: models.CategoryInProgress, To: models.CategoryTodo, Name: "stop"},
+	{From: models.CategoryDone, To: models.CategoryInProgress, Name: "reopen"},
+	{From: models.CategoryDone, To: models.CategoryTodo, Name: "reopen"},
+}
+
+// TransitionError reports a status change that isn't in Transitions or
+// was refused by its guard.
+type TransitionError struct {
+	From   string
+	To     string
+	Reason string
+}
+
+func (e *TransitionError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("cannot change status from %s to %s: %s", e.From, e.To, e.Reason)
+	}
+	return fmt.Sprintf("cannot change status from %s to %s", e.From, e.To)
+}
+
+// Find returns the transition from one category to another, if allowed.
+func Find(from, to string) (Transition, bool) {
+	for _, t := range Transitions {
+		if t.From == from && t.To == to {
+			return t, true
+		}
+	}
+	return Transition{}, false
+}
+
+// Apply moves task from status `from` to status `to` at time `at`, updating
+// StartedAt and CompletedAt. A zero `from` means the task is being created
+// and may start in any status. Apply reports whether the status changed.
+//
+// Entering an in-progress status records when work started, entering a done
+// status records when it finished, and reopening a finished task clears
+// CompletedAt while keeping StartedAt. Sending a task back to a todo status
+// clears both.
+func Apply(task *models.Task, from, to models.TaskStatus, at time.Time) (bool, error) {
+	if from.Name == to.Name && from.Name != "" {
+		return false, nil
+	}
+
+	if from.Name != "" && from.Category != to.Category {
+		transition, ok := Find(from.Category, to.Category)
+		if !ok {
+			return false, &TransitionError{From: from.Name, To: to.Name}
+		}
+		if transition.Guard != nil {
+			if err := transition.Guard(task); err != nil {
+				return false, &TransitionError{From: from.Name, To: to.Name, Reason: err.Error()}
+			}
+		}
+	}
+
+	switch to.Category {
+	case models.CategoryTodo:
+		task.StartedAt = nil
+		task.CompletedAt = nil
+	case models.CategoryInProgress:
+		if task.StartedAt == nil {
+			task.StartedAt = &at
+		}
+		task.CompletedAt = nil
+	case models.CategoryDone:
+		if from.Category != models.CategoryDone || task.CompletedAt == nil {
+			task.CompletedAt = &at
+		}
+	}
+
+	task.Status = to.Name
+	return true, nil
+}
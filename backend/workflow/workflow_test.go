@@ -0,0 +1,114 @@
+package workflow
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"taskmanager/models"
+)
+
+var (
+	todo       = models.TaskStatus{Name: "Pending", Category: models.CategoryTodo}
+	inProgress = models.TaskStatus{Name: "In-Progress", Category: models.CategoryInProgress}
+	review     = models.TaskStatus{Name: "Review", Category: models.CategoryInProgress}
+	done       = models.TaskStatus{Name: "Completed", Category: models.CategoryDone}
+)
+
+func TestApplyOnCreate(t *testing.T) {
+	at := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		status        models.TaskStatus
+		wantStarted   bool
+		wantCompleted bool
+	}{
+		{todo, false, false},
+		{inProgress, true, false},
+		{done, false, true},
+	}
+	for _, tt := range tests {
+		var task models.Task
+		changed, err := Apply(&task, models.TaskStatus{}, tt.status, at)
+		if err != nil || !changed {
+			t.Fatalf("Apply(%s) = %v, %v", tt.status.Name, changed, err)
+		}
+		if task.Status != tt.status.Name {
+			t.Errorf("%s: Status = %q", tt.status.Name, task.Status)
+		}
+		if (task.StartedAt != nil) != tt.wantStarted {
+			t.Errorf("%s: StartedAt = %v", tt.status.Name, task.StartedAt)
+		}
+		if (task.CompletedAt != nil) != tt.wantCompleted {
+			t.Errorf("%s: CompletedAt = %v", tt.status.Name, task.CompletedAt)
+		}
+	}
+}
+
+func TestApplyLifecycle(t *testing.T) {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	task := models.Task{Status: todo.Name}
+
+	if _, err := Apply(&task, todo, inProgress, start); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Apply(&task, inProgress, review, start.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if task.StartedAt == nil || !task.StartedAt.Equal(start) {
+		t.Errorf("moving within a category changed StartedAt to %v", task.StartedAt)
+	}
+
+	finish := start.Add(2 * time.Hour)
+	if _, err := Apply(&task, review, done, finish); err != nil {
+		t.Fatal(err)
+	}
+	if task.CompletedAt == nil || !task.CompletedAt.Equal(finish) {
+		t.Errorf("CompletedAt = %v, want %v", task.CompletedAt, finish)
+	}
+
+	if _, err := Apply(&task, done, inProgress, finish.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if task.CompletedAt != nil || task.StartedAt == nil || !task.StartedAt.Equal(start) {
+		t.Errorf("reopening: StartedAt = %v, CompletedAt = %v", task.StartedAt, task.CompletedAt)
+	}
+
+	if _, err := Apply(&task, inProgress, todo, finish.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if task.StartedAt != nil || task.CompletedAt != nil {
+		t.Errorf("stopping kept StartedAt = %v, CompletedAt = %v", task.StartedAt, task.CompletedAt)
+	}
+}
+
+func TestApplySameStatus(t *testing.T) {
+	task := models.Task{Status: inProgress.Name}
+	changed, err := Apply(&task, inProgress, inProgress, time.Now())
+	if err != nil || changed {
+		t.Errorf("Apply to the same status = %v, %v", changed, err)
+	}
+}
+
+func TestApplyGuard(t *testing.T) {
+	saved := Transitions
+	defer func() { Transitions = saved }()
+
+	Transitions = []Transition{{
+		From: models.CategoryTodo, To: models.CategoryDone, Name: "complete",
+		Guard: func(*models.Task) error { return errors.New("has open subtasks") },
+	}}
+
+	task := models.Task{Status: todo.Name}
+	_, err := Apply(&task, todo, done, time.Now())
+	var transitionErr *TransitionError
+	if !errors.As(err, &transitionErr) || transitionErr.Reason != "has open subtasks" {
+		t.Errorf("guarded transition error = %v", err)
+	}
+	if _, err := Apply(&task, todo, inProgress, time.Now()); err == nil {
+		t.Error("expected a transition missing from Transitions to fail")
+	}
+	if task.Status != todo.Name || task.CompletedAt != nil {
+		t.Errorf("failed transitions changed the task: %+v", task)
+	}
+}
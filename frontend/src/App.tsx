@@ -56,10 +56,10 @@ function TaskManagerContent() {
         wsInstance = ws;
         
         // Set up message handler
-        ws.onMessage((message: string) => {
+        ws.onMessage((message: any) => {
           console.log('WebSocket message received:', message);
           
-          if (message === 'update') {
+          if (message === 'update' || message?.type === 'tasks.bulk_updated') {
             console.log('Received update notification, refreshing tasks...');
             fetchTasks();
           }